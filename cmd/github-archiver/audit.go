@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/report"
+)
+
+// fileAuditor appends audit records to a file as JSON Lines, syncing each
+// one to disk before the next request can be recorded
+type fileAuditor struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Audit implements github.Auditor
+func (a *fileAuditor) Audit(record github.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return a.f.Sync()
+}
+
+// Close closes the audit log
+func (a *fileAuditor) Close() error {
+	return a.f.Close()
+}
+
+// webhookAuditor posts each audit record to a URL as a JSON object
+type webhookAuditor struct {
+	url string
+}
+
+// Audit implements github.Auditor
+func (a *webhookAuditor) Audit(record github.AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), report.WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create audit request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post audit record: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post audit record: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Close has nothing to release for a webhook
+func (a *webhookAuditor) Close() error {
+	return nil
+}
+
+// auditSink is an audit log destination
+type auditSink interface {
+	github.Auditor
+	io.Closer
+}
+
+// openAuditor opens the -audit-log destination: an http(s) URL receives
+// each record as a webhook, anything else is a file opened for appending
+func openAuditor(dest string) (auditSink, error) {
+	if strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://") {
+		return &webhookAuditor{url: dest}, nil
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &fileAuditor{f: f}, nil
+}
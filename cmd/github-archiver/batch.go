@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/manifest"
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// batches splits repositories into consecutive chunks of at most size
+// repositories. A size of zero or less yields a single chunk.
+func batches(repos []github.Repository, size int) [][]github.Repository {
+	if size <= 0 || len(repos) <= size {
+		return [][]github.Repository{repos}
+	}
+	var chunks [][]github.Repository
+	for start := 0; start < len(repos); start += size {
+		end := start + size
+		if end > len(repos) {
+			end = len(repos)
+		}
+		chunks = append(chunks, repos[start:end])
+	}
+	return chunks
+}
+
+// nextBatch is called before each repository is archived. At a batch
+// boundary it logs progress and where to resume, checkpoints the manifest,
+// then pauses so rate limits can recover.
+func nextBatch(ctx context.Context, opts *options, manifestWriter *manifest.Writer, repos []github.Repository, i int) error {
+	if opts.batchSize <= 0 || i == 0 || i%opts.batchSize != 0 {
+		return nil
+	}
+
+	total := (len(repos) + opts.batchSize - 1) / opts.batchSize
+	if err := manifestWriter.Sync(); err != nil {
+		logger.Error("%v", err)
+	}
+	logger.Info("Batch %d/%d archived (%d of %d repositories); to continue from here use -resume-from %s/%s",
+		i/opts.batchSize, total, i, len(repos), repos[i].Owner, repos[i].Name)
+	return pauseBatch(ctx, opts.batchPause)
+}
+
+// pauseBatch waits out the pause between batches, returning early if the
+// context is canceled
+func pauseBatch(ctx context.Context, pause time.Duration) error {
+	if pause <= 0 {
+		return nil
+	}
+	logger.Info("Pausing %v before the next batch...", pause)
+	return util.SleepCtx(ctx, pause)
+}
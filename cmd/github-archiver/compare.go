@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/report"
+)
+
+// compareNamespaces lists the target and an archive namespace side by side
+// and reports repositories present in only one of them, and archive copies
+// that were never marked archived. Archive copies are matched by the name
+// the archiver gives them, including any -archive-name-prefix and suffix.
+func compareNamespaces(ctx context.Context, client *github.Client, opts *options, reporters []report.Reporter) error {
+	archive := opts.compareNamespace
+	logger.Info("Comparing %s with archive namespace %s...", opts.target, archive)
+
+	source, err := client.ListRepositories(ctx, opts.target, opts.org, github.ListFilters{})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", opts.target, err)
+	}
+	archiveOrg, err := client.IsOrganization(ctx, archive)
+	if err != nil {
+		return err
+	}
+	copies, err := client.ListRepositories(ctx, archive, archiveOrg, github.ListFilters{})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", archive, err)
+	}
+
+	byName := make(map[string]github.Repository, len(copies))
+	for _, repo := range copies {
+		byName[strings.ToLower(repo.Name)] = repo
+	}
+
+	var entries []report.Entry
+	counts := make(map[string]int)
+	add := func(repo github.Repository, status, signal string) {
+		entries = append(entries, report.Entry{
+			Owner:        repo.Owner,
+			Name:         repo.Name,
+			LastActivity: repo.LastActivity,
+			Status:       status,
+			Signal:       signal,
+		})
+		counts[status]++
+	}
+
+	for _, repo := range source {
+		name := strings.ToLower(opts.namePrefix + repo.Name + opts.nameSuffix)
+		archived, ok := byName[name]
+		switch {
+		case !ok && repo.IsArchived:
+			add(repo, report.StatusSourceOnly, "archived in place, no copy")
+		case !ok:
+			add(repo, report.StatusSourceOnly, "no copy")
+		case !archived.IsArchived:
+			add(archived, report.StatusMismatch, "copy of "+repo.Owner+"/"+repo.Name+" not archived")
+		case !repo.IsArchived:
+			add(repo, report.StatusReconciled, "original kept")
+		default:
+			add(repo, report.StatusReconciled, "")
+		}
+		delete(byName, name)
+	}
+	github.SortRepositories(copies)
+	for _, repo := range copies {
+		if _, ok := byName[strings.ToLower(repo.Name)]; !ok {
+			continue
+		}
+		signal := "no original"
+		if !repo.IsArchived {
+			signal = "no original, copy not archived"
+		}
+		add(repo, report.StatusArchiveOnly, signal)
+	}
+
+	if len(reporters) == 0 {
+		reporters = append(reporters, report.NewWriterReporter(os.Stdout, report.TableFormat))
+	}
+	renderReport(ctx, reporters, entries)
+	logger.Info("Compared %d source and %d archive repositories: %d reconciled, %d source only, %d archive only, %d mismatched",
+		len(source), len(copies), counts[report.StatusReconciled], counts[report.StatusSourceOnly],
+		counts[report.StatusArchiveOnly], counts[report.StatusMismatch])
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/archiver"
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/manifest"
+	"github.com/eyedeekay/github-archiver/pkg/report"
+)
+
+// pastDeadline reports whether -max-runtime has run out, as opposed to the
+// run being interrupted
+func pastDeadline(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// deferRepositories marks the candidates archiving did not reach before the
+// -max-runtime deadline, lists them and, with -deferred-file, writes them
+// as JSON Lines records that -archive-from accepts on the next run
+func deferRepositories(opts *options, strategy archiver.Strategy, repos []github.Repository, entries []report.Entry) {
+	logger.Warn("Maximum runtime of %v reached; %d repositories deferred:", opts.maxRuntime.Round(time.Second), len(repos))
+	for i, repo := range repos {
+		logger.Warn("  - %s/%s", repo.Owner, repo.Name)
+		entries[i].Status = report.StatusDeferred
+	}
+	if opts.deferredFile == "" {
+		logger.Info("Pass -deferred-file to save them for -archive-from")
+		return
+	}
+
+	if err := writeDeferred(opts.deferredFile, strategy, repos); err != nil {
+		logger.Error("%v", err)
+		return
+	}
+	logger.Info("Deferred repositories written to %s; continue with -archive-from %s", opts.deferredFile, opts.deferredFile)
+}
+
+// writeDeferred replaces the file with one manifest record per deferred
+// repository
+func writeDeferred(path string, strategy archiver.Strategy, repos []github.Repository) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, repo := range repos {
+		record := manifest.Record{
+			Time:     time.Now().UTC(),
+			Owner:    repo.Owner,
+			Name:     repo.Name,
+			Strategy: string(strategy),
+			Status:   report.StatusDeferred,
+		}
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode deferred %s: %w", repo.Name, err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write deferred repositories: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/eyedeekay/github-archiver/pkg/report"
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// runDiff implements the diff subcommand: it compares two JSON reports and
+// writes the delta as JSON
+func runDiff(w io.Writer, args []string) error {
+	if len(args) != 2 {
+		return util.Usagef("usage: github-archiver diff <earlier-report.json> <later-report.json>")
+	}
+	before, err := report.ReadJSON(args[0])
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+	after, err := report.ReadJSON(args[1])
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report.Compare(before, after)); err != nil {
+		return fmt.Errorf("failed to write diff: %w", err)
+	}
+	return nil
+}
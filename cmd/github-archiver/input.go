@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// repoRecord is a single line of a JSON Lines repository list
+type repoRecord struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+}
+
+// readRecords parses a JSON Lines list of repositories, validating each record
+func readRecords(r io.Reader) ([]repoRecord, error) {
+	var records []repoRecord
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var record repoRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", line, err)
+		}
+		if record.Owner == "" || record.Name == "" {
+			return nil, fmt.Errorf("line %d: record must have both owner and name", line)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// readRecordsFile reads a JSON Lines repository list from a file
+func readRecordsFile(path string) ([]repoRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository list: %w", err)
+	}
+	defer f.Close()
+
+	records, err := readRecords(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository list %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// readRepoNames parses owner/name lines, ignoring blank lines and comments
+func readRepoNames(r io.Reader) ([]repoRecord, error) {
+	var records []repoRecord
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		owner, name, ok := strings.Cut(text, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("line %d: expected owner/name, got %q", line, text)
+		}
+		records = append(records, repoRecord{Owner: owner, Name: name})
+	}
+	return records, scanner.Err()
+}
+
+// readInputRecords reads the repository list selected by -archive-from or
+// -repos-from-stdin. It returns nil if neither is set, so stdin is only
+// consumed when explicitly requested.
+func readInputRecords(opts *options) ([]repoRecord, error) {
+	switch {
+	case opts.archiveFrom != "" && opts.reposFromStdin:
+		return nil, fmt.Errorf("-archive-from and -repos-from-stdin cannot be combined")
+	case opts.archiveFrom != "":
+		return readRecordsFile(opts.archiveFrom)
+	case opts.reposFromStdin:
+		records, err := readRepoNames(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read repositories from stdin: %w", err)
+		}
+		return records, nil
+	}
+	return nil, nil
+}
+
+// validateRecords fetches the current state of each listed repository
+func validateRecords(ctx context.Context, client *github.Client, records []repoRecord) ([]github.Repository, error) {
+	var repos []github.Repository
+	for _, record := range records {
+		repo, err := client.GetRepository(ctx, record.Owner, record.Name)
+		if errors.Is(err, github.ErrNotFound) {
+			logger.Warn("Skipping %s/%s - repository not found", record.Owner, record.Name)
+			continue
+		}
+		if util.ForceProcessing(err) {
+			return nil, fmt.Errorf("failed to validate %s/%s: %w", record.Owner, record.Name, err)
+		}
+		if err != nil {
+			continue
+		}
+		if repo.IsArchived {
+			logger.Info("Skipping %s/%s - already archived", repo.Owner, repo.Name)
+			continue
+		}
+		repos = append(repos, repo)
+	}
+	return github.DedupeRepositories(repos), nil
+}
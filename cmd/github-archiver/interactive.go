@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+)
+
+// pageSize is the number of candidates shown per checklist page
+const pageSize = 20
+
+// errSelectionAborted is returned when the user quits the checklist
+// without confirming a selection
+var errSelectionAborted = errors.New("interactive selection aborted")
+
+// isTerminal reports whether both stdin and stdout are attached to a terminal
+func isTerminal() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stdout} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// selectInteractively presents the candidates as a paged checklist and
+// returns the ones left selected and the ones the user deselected. Every
+// candidate starts selected.
+func selectInteractively(in io.Reader, out io.Writer, repos []github.Repository, now time.Time) (selected, deselected []github.Repository, err error) {
+	keep := make([]bool, len(repos))
+	for i := range keep {
+		keep[i] = true
+	}
+
+	scanner := bufio.NewScanner(in)
+	page := 0
+	pages := (len(repos) + pageSize - 1) / pageSize
+	for {
+		printChecklist(out, repos, keep, page, pages, now)
+		fmt.Fprint(out, "Toggle numbers or ranges (e.g. 3 5-8), [a]ll, [n]one, [>] next page, [<] previous page, [d]one, [q]uit: ")
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, nil, fmt.Errorf("failed to read selection: %w", err)
+			}
+			return nil, nil, errSelectionAborted
+		}
+
+		input := strings.TrimSpace(scanner.Text())
+		switch input {
+		case "":
+		case "a", "all":
+			setAll(keep, true)
+		case "n", "none":
+			setAll(keep, false)
+		case ">":
+			if page < pages-1 {
+				page++
+			}
+		case "<":
+			if page > 0 {
+				page--
+			}
+		case "d", "done":
+			for i, repo := range repos {
+				if keep[i] {
+					selected = append(selected, repo)
+				} else {
+					deselected = append(deselected, repo)
+				}
+			}
+			return selected, deselected, nil
+		case "q", "quit":
+			return nil, nil, errSelectionAborted
+		default:
+			if err := toggle(keep, input); err != nil {
+				fmt.Fprintf(out, "%v\n", err)
+			}
+		}
+	}
+}
+
+// printChecklist writes one page of the checklist
+func printChecklist(out io.Writer, repos []github.Repository, keep []bool, page, pages int, now time.Time) {
+	count := 0
+	for _, k := range keep {
+		if k {
+			count++
+		}
+	}
+	fmt.Fprintf(out, "\nCandidates for archiving, page %d/%d (%d of %d selected):\n", page+1, pages, count, len(repos))
+
+	end := (page + 1) * pageSize
+	if end > len(repos) {
+		end = len(repos)
+	}
+	for i := page * pageSize; i < end; i++ {
+		mark := " "
+		if keep[i] {
+			mark = "x"
+		}
+		repo := repos[i]
+		days := int(now.Sub(repo.LastActivity).Hours() / 24)
+		fmt.Fprintf(out, "  [%s] %3d. %s/%s (last activity %s, %d days ago)\n",
+			mark, i+1, repo.Owner, repo.Name, repo.LastActivity.Format("2006-01-02"), days)
+	}
+}
+
+// toggle flips the candidates named by a list of 1-based numbers and
+// ranges. Nothing is changed if any entry is invalid.
+func toggle(keep []bool, input string) error {
+	var ranges [][2]int
+	for _, field := range strings.Fields(strings.ReplaceAll(input, ",", " ")) {
+		lo, hi, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return fmt.Errorf("invalid selection %q", field)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return fmt.Errorf("invalid selection %q", field)
+			}
+		}
+		if first < 1 || last > len(keep) || first > last {
+			return fmt.Errorf("selection %q is out of range 1-%d", field, len(keep))
+		}
+		ranges = append(ranges, [2]int{first, last})
+	}
+	for _, r := range ranges {
+		for i := r[0] - 1; i < r[1]; i++ {
+			keep[i] = !keep[i]
+		}
+	}
+	return nil
+}
+
+// setAll selects or deselects every candidate
+func setAll(keep []bool, value bool) {
+	for i := range keep {
+		keep[i] = value
+	}
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/eyedeekay/github-archiver/pkg/analyzer"
@@ -14,96 +18,339 @@ import (
 	"github.com/eyedeekay/github-archiver/pkg/util"
 )
 
+// options holds the parsed command-line flags
+type options struct {
+	token            string
+	target           string
+	dryRun           bool
+	org              bool
+	threshold        int
+	verbose          bool
+	quiet            bool
+	force            bool
+	reportTemplate   string
+	search           bool
+	team             string
+	maxFraction      float64
+	confirmCount     int
+	checkPulls       bool
+	minInactivity    string
+	maxInactivity    string
+	strategy         string
+	allowDelete      bool
+	atticRepo        string
+	atticShallow     bool
+	forkTimeout      time.Duration
+	forkTimeoutMax   time.Duration
+	eventsFile       string
+	eventsFD         int
+	excludeFiles     string
+	skipTemplates    bool
+	skipMirrors      bool
+	retryBudget      int
+	breakerLimit     int
+	archiveFrom      string
+	logLevel         string
+	logFormat        string
+	reposFromStdin   bool
+	checkWorkflows   bool
+	reportFormat     string
+	namePrefix       string
+	nameSuffix       string
+	manifestPath     string
+	resumeFrom       string
+	affiliation      string
+	repoType         string
+	thresholdSrc     string
+	maxRPS           float64
+	sandbox          string
+	sandboxCleanup   bool
+	beforeYear       int
+	onError          string
+	maxCommits       int
+	sinceFile        string
+	listNamespaces   bool
+	includeRegex     string
+	excludeRegex     string
+	ignoreFile       string
+	reportFile       string
+	reportWebhook    string
+	summaryFormat    string
+	onlyEmpty        bool
+	quarantine       string
+	quietErrors      bool
+	minWatchers      int
+	userAgent        string
+	version          bool
+	exportGist       bool
+	reportActive     bool
+	skipProtected    bool
+	batchSize        int
+	batchPause       time.Duration
+	interactive      bool
+	scoreThreshold   float64
+	weightRecency    float64
+	weightStars      float64
+	weightIssues     float64
+	weightSize       float64
+	deleteCheck      bool
+	generatedAfter   string
+	generatedNames   string
+	generatedLangs   string
+	allAdmin         bool
+	repoTimeout      time.Duration
+	compareNamespace string
+	departedAfter    string
+	keepLatest       int
+	groupRegex       string
+	requireTopic     string
+	exportSettings   string
+	namespaceType    string
+	notifyGrace      string
+	objectionLabel   string
+	fixturesDir      string
+	deleteAfter      string
+	deleteForks      bool
+	probeOnly        bool
+	teamDiscussion   bool
+	skipArchived     bool
+	skipArchiveNames bool
+	protectBranch    bool
+	noDescription    bool
+	verifySigs       bool
+	maxRuntime       time.Duration
+	deferredFile     string
+	archivePrivate   bool
+	checkSecurity    bool
+	copyCollabs      bool
+	auditLog         string
+	described        bool
+
+	// clock provides the time for every cutoff and deadline of the run
+	clock util.Clock
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		fmt.Println(versionString())
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := runDiff(os.Stdout, os.Args[2:]); err != nil {
+			kind := github.ClassifyError(err)
+			fmt.Fprintf(os.Stderr, "%s: %v\n", kind, err)
+			os.Exit(kind.ExitCode())
+		}
+		return
+	}
+
 	// Define command-line flags
-	token := flag.String("token", "", "GitHub personal access token")
-	target := flag.String("target", "", "GitHub username or organization name")
-	dryRun := flag.Bool("dry-run", false, "Perform a dry run without making changes")
-	org := flag.Bool("org", false, "Work on a github organization")
-	inactivityThreshold := flag.Int("threshold", 2, "Inactivity threshold in years")
-	verbose := flag.Bool("verbose", false, "Enable verbose (debug) logging")
-	quiet := flag.Bool("quiet", false, "Show only warnings and errors")
-	force := flag.Bool("force", false, "Force processing even if errors occur")
+	opts := &options{clock: util.SystemClock{}}
+	flag.StringVar(&opts.token, "token", "", "GitHub personal access token")
+	flag.StringVar(&opts.target, "target", "", "GitHub username or organization name")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "Perform a dry run without making changes")
+	flag.BoolVar(&opts.org, "org", false, "Work on a github organization")
+	flag.IntVar(&opts.threshold, "threshold", 0, "Inactivity threshold in years (default: depends on -strategy: 1 for archive, 2 for fork and attic, 3 for delete)")
+	flag.StringVar(&opts.logLevel, "log-level", "", "Log level: debug, info, warn, error, fatal or silent (default info)")
+	flag.StringVar(&opts.logFormat, "log-format", "text", "Log format: text or logfmt (ts=... level=... msg=\"...\")")
+	flag.BoolVar(&opts.verbose, "verbose", false, "Enable verbose (debug) logging (deprecated: use -log-level=debug)")
+	flag.BoolVar(&opts.quiet, "quiet", false, "Show only warnings and errors (deprecated: use -log-level=warn)")
+	flag.BoolVar(&opts.force, "force", false, "Downgrade setup failures such as a failed listing to errors and keep going; exclude and ignore lists must always load")
+	flag.StringVar(&opts.reportTemplate, "report-template", "", "Go text/template rendered once per repository (prefix with @ to read from a file)")
+	flag.BoolVar(&opts.search, "search", false, "Use the search API to find candidates pushed before the threshold, falling back to a full listing when results are capped")
+	flag.StringVar(&opts.team, "team", "", "Only process repositories of the given team(s), as comma-separated org/team-slug")
+	flag.Float64Var(&opts.maxFraction, "max-archive-fraction", 0.8, "Abort if more than this fraction of repositories would be archived (1 disables the check)")
+	flag.IntVar(&opts.confirmCount, "confirm-count", 0, "Abort if more than this many repositories would be archived (0 disables the check)")
+	flag.BoolVar(&opts.checkPulls, "skip-open-prs", false, "Spare repositories with an open pull request updated within the threshold")
+	flag.StringVar(&opts.minInactivity, "min-inactivity", "", "Minimum inactivity to select a repository, e.g. 2y or 180d (overrides -threshold)")
+	flag.StringVar(&opts.deleteAfter, "min-inactivity-for-delete", "", "Two tiers: apply -strategy only to repositories inactive for at least this long, e.g. 4y, and archive the rest in place (requires -allow-delete)")
+	flag.StringVar(&opts.maxInactivity, "max-inactivity", "", "Maximum inactivity to select a repository, e.g. 4y (default: unbounded)")
+	flag.StringVar(&opts.strategy, "strategy", "fork", "Archive strategy: fork (copy into the archive namespace), archive (mark archived in place), delete, or attic (commit into a directory of -attic-repo)")
+	flag.BoolVar(&opts.allowDelete, "allow-delete", false, "Allow deleting original repositories (required by the delete strategy)")
+	flag.StringVar(&opts.atticRepo, "attic-repo", "", "With the attic strategy, the existing owner/name repository that each source is committed into under owner/repo/")
+	flag.BoolVar(&opts.atticShallow, "attic-shallow", false, "With the attic strategy, import only the head of each source instead of merging in its history")
+	flag.DurationVar(&opts.forkTimeout, "fork-timeout", archiver.DefaultForkTimeout, "Base time to wait for a fork, extended by one second per MB of repository size")
+	flag.DurationVar(&opts.forkTimeoutMax, "fork-timeout-max", archiver.DefaultForkTimeoutMax, "Maximum time to wait for a fork regardless of repository size")
+	flag.StringVar(&opts.eventsFile, "events-file", "", "Write newline-delimited JSON events to this file (- for stdout)")
+	flag.IntVar(&opts.eventsFD, "events-fd", 0, "Write newline-delimited JSON events to this already-open file descriptor")
+	flag.StringVar(&opts.excludeFiles, "exclude-file", "", "Comma-separated files or http(s) URLs listing repository patterns never to process")
+	flag.BoolVar(&opts.skipTemplates, "skip-templates", true, "Never archive template repositories")
+	flag.BoolVar(&opts.skipMirrors, "skip-mirrors", false, "Never archive mirror repositories")
+	flag.IntVar(&opts.retryBudget, "retry-budget", github.DefaultRetryBudget, "Maximum number of request retries across the whole run")
+	flag.IntVar(&opts.breakerLimit, "breaker-threshold", github.DefaultBreakerThreshold, "Abort after this many consecutive transient API failures (0 disables)")
+	flag.StringVar(&opts.archiveFrom, "archive-from", "", "Archive the repositories listed in this JSON Lines file of {\"owner\",\"name\"} records, skipping listing and analysis")
+	flag.BoolVar(&opts.reposFromStdin, "repos-from-stdin", false, "Archive the owner/name repositories read from stdin, one per line, skipping listing and analysis")
+	flag.BoolVar(&opts.checkWorkflows, "check-workflows", false, "Treat recent GitHub Actions workflow runs as activity")
+	flag.StringVar(&opts.reportFormat, "report-format", "", "Print a per-repository report in this format: table or json")
+	flag.StringVar(&opts.namePrefix, "archive-name-prefix", "", "Prefix added to the name of archived forks, e.g. archived-")
+	flag.StringVar(&opts.nameSuffix, "archive-name-suffix", "", "Suffix added to the name of archived forks")
+	flag.StringVar(&opts.manifestPath, "manifest", "", "Append a JSON Lines record of each processed repository to this file")
+	flag.StringVar(&opts.resumeFrom, "resume-from", "", "Skip repositories ordered before this owner/name to continue an interrupted run")
+	flag.StringVar(&opts.affiliation, "affiliation", "", "List the authenticated user's repositories by affiliation: comma-separated owner, collaborator, organization_member")
+	flag.StringVar(&opts.repoType, "repo-type", "", "Repository type filter passed to the listing, e.g. owner or member for users, sources or forks for organizations")
+	flag.StringVar(&opts.thresholdSrc, "threshold-source", "pushed", "Repository timestamp used as the base for last activity: pushed, updated or created")
+	flag.Float64Var(&opts.maxRPS, "max-rps", github.DefaultMaxRPS, "Maximum API requests per second across the whole run (0 disables the limit)")
+	flag.StringVar(&opts.sandbox, "dry-run-archive", "", "Test the fork flow by forking candidates into this sandbox namespace, never touching the originals")
+	flag.BoolVar(&opts.sandboxCleanup, "dry-run-archive-cleanup", false, "Delete the test forks created by -dry-run-archive once verified")
+	flag.IntVar(&opts.beforeYear, "inactive-before-year", 0, "Select repositories with no activity since January 1 of this year, e.g. 2022 (overrides -threshold)")
+	flag.StringVar(&opts.onError, "on-error", onErrorContinue, "What to do when a single repository fails: continue with the next one, or stop the run")
+	flag.IntVar(&opts.maxCommits, "max-commits", 0, "Only archive repositories with at most this many commits (0 disables the check; costs one request per candidate)")
+	flag.StringVar(&opts.sinceFile, "since-file", "", "Remember the last successful run time in this file and skip analyzing repositories active since then")
+	flag.BoolVar(&opts.listNamespaces, "list-namespaces", false, "List the archive namespaces the candidates would use, check that each exists, and exit")
+	flag.StringVar(&opts.includeRegex, "include-regex", "", "Only process repositories whose owner/name matches this regular expression")
+	flag.StringVar(&opts.excludeRegex, "exclude-regex", "", "Never process repositories whose owner/name matches this regular expression")
+	flag.StringVar(&opts.ignoreFile, "ignore-file", "", "Gitignore-style file of repository patterns never to process (default: .archiverignore in the working directory, if present)")
+	flag.StringVar(&opts.reportFile, "report-file", "", "Write the per-repository report as JSON to this file")
+	flag.BoolVar(&opts.teamDiscussion, "report-team-discussion", false, "After the run, post a summary to a new discussion in each -team (needs the write:discussion scope; skipped in dry runs)")
+	flag.StringVar(&opts.reportWebhook, "report-webhook", "", "POST the per-repository report as JSON to this http(s) URL")
+	flag.StringVar(&opts.summaryFormat, "summary-format", summaryText, "Format of the end-of-run summary: text (log lines) or json (a single object on stdout; logs go to stderr)")
+	flag.BoolVar(&opts.onlyEmpty, "only-empty", false, "Select repositories with no commits, regardless of activity, instead of inactive ones")
+	flag.StringVar(&opts.quarantine, "quarantine", "", "Two-phase archiving: mark candidates with a notice and the pending-archive topic, and only archive them on a later run once this period, e.g. 30d, has elapsed")
+	flag.BoolVar(&opts.quietErrors, "quiet-errors", false, fmt.Sprintf("Only log the first %d error continuance messages and count the rest in the summary", continuanceLimit))
+	flag.IntVar(&opts.minWatchers, "min-watchers", 0, "Spare inactive repositories with at least this many watchers (0 disables the check; costs one request per candidate)")
+	flag.StringVar(&opts.userAgent, "user-agent", defaultUserAgent(), "User-Agent sent with every API request")
+	flag.BoolVar(&opts.exportGist, "export-gist", false, "Before archiving, save a summary of each repository's open issues and pull requests to a secret gist")
+	flag.BoolVar(&opts.reportActive, "report-include-active", false, "Include the repositories that were not selected in reports, with the signal that kept them")
+	flag.BoolVar(&opts.skipProtected, "skip-protected", false, "Spare inactive repositories whose default branch has branch protection or a ruleset")
+	flag.IntVar(&opts.batchSize, "batch-size", 0, "Analyze and archive repositories in batches of this many, checkpointing between batches (0 processes everything at once)")
+	flag.DurationVar(&opts.batchPause, "batch-pause", 0, "Time to pause between batches so rate limits can recover")
+	flag.BoolVar(&opts.interactive, "interactive", false, "Review the candidates in a checklist and deselect ones to keep before archiving (ignored unless run in a terminal)")
+	flag.Float64Var(&opts.scoreThreshold, "score-threshold", 0, "Only archive inactive repositories whose 0-100 archivability score reaches this value (0 disables scoring)")
+	flag.Float64Var(&opts.weightRecency, "weight-recency", analyzer.DefaultWeights.Recency, "Score weight of time since the last activity")
+	flag.Float64Var(&opts.weightStars, "weight-stars", analyzer.DefaultWeights.Stars, "Score weight of having few stars")
+	flag.Float64Var(&opts.weightIssues, "weight-issues", analyzer.DefaultWeights.Issues, "Score weight of having few open issues and pull requests")
+	flag.Float64Var(&opts.weightSize, "weight-size", analyzer.DefaultWeights.Size, "Score weight of being small")
+	flag.BoolVar(&opts.deleteCheck, "dry-run-delete-check", false, "Deprecated: every dry run now reports candidates the token lacks the admin permission to archive or delete")
+	flag.StringVar(&opts.generatedAfter, "generated-threshold", "", "Select repositories that look generated after this shorter inactivity, e.g. 90d (disabled by default)")
+	flag.StringVar(&opts.generatedNames, "generated-names", strings.Join(analyzer.DefaultGeneratedRules.Names, ","), "Comma-separated name globs of generated repositories")
+	flag.StringVar(&opts.generatedLangs, "generated-languages", strings.Join(analyzer.DefaultGeneratedRules.Languages, ","), "Comma-separated languages; repositories containing only these look generated (empty disables the language check)")
+	flag.BoolVar(&opts.allAdmin, "all-admin", false, "Process every repository the token has admin rights on, across all owners, archiving each into <owner>-archive")
+	flag.DurationVar(&opts.repoTimeout, "repo-timeout", 0, "Abandon a repository whose whole archive sequence takes longer than this and move on (0 disables the limit)")
+	flag.StringVar(&opts.compareNamespace, "compare-namespaces", "", "Audit the target against this archive namespace, reporting repositories present in only one and copies not marked archived, then exit")
+	flag.StringVar(&opts.departedAfter, "departed-threshold", "", "Select organization repositories whose contributors have all left the organization after this shorter inactivity, e.g. 180d (disabled by default)")
+	flag.IntVar(&opts.keepLatest, "keep-latest-n", 0, "Spare the N most recently active repositories of each name group, regardless of age (0 disables)")
+	flag.StringVar(&opts.groupRegex, "group-regex", analyzer.DefaultGroupPattern, "Regular expression grouping repository names for -keep-latest-n; the first capture group names the group")
+	flag.StringVar(&opts.requireTopic, "require-topic", "", "Only delete repositories carrying this topic, e.g. approved-for-archive; others are kept")
+	flag.StringVar(&opts.exportSettings, "export-settings", "", "Before archiving, save each repository's webhooks, branch protection, collaborators and secret names as JSON under this directory")
+	flag.StringVar(&opts.namespaceType, "namespace-type", string(github.NamespaceAuto), "Kind of account the archive namespaces are: auto, org or user (org and user save one API request per namespace)")
+	flag.StringVar(&opts.notifyGrace, "notify-grace", "", "Open a \"This repository will be archived\" issue mentioning the admins of each candidate, and only archive it on a later run once this grace period, e.g. 30d, has elapsed without objection")
+	flag.StringVar(&opts.objectionLabel, "objection-label", archiver.DefaultObjectionLabel, "Label that, added to the notice issue, cancels archiving under -notify-grace")
+	flag.StringVar(&opts.fixturesDir, "fixtures-dir", "", "Read GitHub data from this directory of JSON fixtures instead of the API, for demos and tests without a token; writes are only simulated")
+	flag.BoolVar(&opts.deleteForks, "delete-active-forks", false, "Delete, rather than archive, inactive forks whose upstream was pushed to within the inactivity threshold, since their code lives upstream (requires -allow-delete)")
+	flag.BoolVar(&opts.probeOnly, "probe-only", false, "Print a table of every activity signal's timestamp per repository, for tuning thresholds, and exit without selecting or archiving anything")
+	flag.BoolVar(&opts.skipArchived, "exclude-recently-archived", false, "Skip repositories whose archive copy already exists in the archive namespace (costs one listing per namespace)")
+	flag.BoolVar(&opts.skipArchiveNames, "exclude-archive-names", false, "Skip repositories that look like archive copies: owned by an archive namespace or named with -archive-name-prefix or -archive-name-suffix")
+	flag.BoolVar(&opts.protectBranch, "protect-archive-branch", false, "With the fork strategy, protect each fork's default branch against force pushes and deletion before archiving it")
+	flag.BoolVar(&opts.noDescription, "only-no-description", false, "Only consider repositories without a description, the most obviously abandoned ones; still subject to the inactivity threshold")
+	flag.BoolVar(&opts.described, "only-described", false, "Only consider repositories with a description; the inverse of -only-no-description")
+	flag.BoolVar(&opts.verifySigs, "verify-signatures", false, "With the fork strategy, check that each fork's default branch head has the same signature verification as the original's before deleting or archiving anything")
+	flag.DurationVar(&opts.maxRuntime, "max-runtime", 0, "Stop starting new repositories after the run has taken this long, finishing the one in flight and reporting the rest as deferred (0 disables the limit)")
+	flag.StringVar(&opts.deferredFile, "deferred-file", "", "With -max-runtime, write the deferred repositories to this file as JSON Lines for -archive-from")
+	flag.BoolVar(&opts.archivePrivate, "archive-private", false, "With the fork strategy, only produce private archives: public repositories, whose forks cannot be made private, fail before they are forked")
+	flag.BoolVar(&opts.checkSecurity, "check-security", false, "Spare repositories with a Dependabot pull request or an open security advisory updated within the threshold (up to two extra API requests per candidate)")
+	flag.BoolVar(&opts.copyCollabs, "copy-collaborators", false, "With the fork strategy, give the original's direct collaborators read access to the fork before the original can be deleted")
+	flag.StringVar(&opts.auditLog, "audit-log", "", "Record every state-changing API request, including failed ones, with the token's user, as JSON Lines appended to this file or posted to this http(s) URL")
+	flag.BoolVar(&opts.version, "version", false, "Print version information and exit")
 	flag.Parse()
-	util.FORCE_PROCESSING = *force
 
-	// Configure logging level
-	if *verbose {
-		logger.SetDefaultLevel(logger.DebugLevel)
-		logger.Debug("Debug logging enabled")
-	} else if *quiet {
-		logger.SetDefaultLevel(logger.WarnLevel)
+	if opts.version {
+		fmt.Println(versionString())
+		return
 	}
 
-	// Validate required flags
-	if *token == "" || *target == "" {
-		flag.Usage()
-		os.Exit(1)
+	// Create a context that is canceled on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, opts); err != nil {
+		err = github.ExplainError(err)
+		kind := github.ClassifyError(err)
+		fmt.Fprintf(os.Stderr, "%s: %v\n", kind, err)
+		if kind == util.UsageError {
+			flag.Usage()
+		}
+		stop()
+		os.Exit(kind.ExitCode())
 	}
+}
 
-	// Create a context that can be canceled
-	ctx := context.Background()
+// -on-error modes
+const (
+	onErrorContinue = "continue"
+	onErrorStop     = "stop"
+)
 
-	// Initialize GitHub client
-	logger.Debug("Initializing GitHub client")
-	client, err := github.NewClient(ctx, *token)
-	if util.ForceProcessing(err) {
-		logger.Fatal("Failed to create GitHub client: %v", err)
-	}
+// -summary-format values
+const (
+	summaryText = "text"
+	summaryJSON = "json"
+)
 
-	// Create the repository analyzer
-	repoAnalyzer := analyzer.NewAnalyzer(client, time.Duration(*inactivityThreshold)*365*24*time.Hour)
-	logger.Debug("Repository analyzer initialized with %d year threshold", *inactivityThreshold)
+// allAffiliations lists every repository the authenticated user can access
+const allAffiliations = "owner,collaborator,organization_member"
 
-	// Create the repository archiver
-	repoArchiver := archiver.NewArchiver(client)
-	logger.Debug("Repository archiver initialized")
+// errUsage is returned when required flags are missing
+var errUsage = errors.New("-token (or -fixtures-dir) and -target (or -team or -all-admin) are required")
 
-	// 1. Fetch all repositories for the target
-	logger.Info("Fetching repositories for %s...", *target)
-	repos, err := client.ListRepositories(ctx, *target, *org)
-	if util.ForceProcessing(err) {
-		logger.Fatal("Failed to list repositories: %v", err)
+// configureLogging applies the format and verbosity flags to the default
+// logger. -log-level takes precedence over the deprecated -verbose and -quiet
+// aliases.
+func configureLogging(opts *options) error {
+	format, err := logger.ParseFormat(opts.logFormat)
+	if err != nil {
+		return err
 	}
-	logger.Info("Found %d repositories for %s", len(repos), *target)
+	logger.SetDefaultFormat(format)
 
-	// 2. Analyze repositories for inactivity
-	logger.Info("Analyzing repository activity...")
-	inactiveRepos, err := repoAnalyzer.FindInactiveRepositories(ctx, repos)
-	if util.ForceProcessing(err) {
-		logger.Fatal("Failed to analyze repositories: %v", err)
+	switch {
+	case opts.logLevel != "":
+		level, err := logger.ParseLevel(opts.logLevel)
+		if err != nil {
+			return err
+		}
+		logger.SetDefaultLevel(level)
+	case opts.verbose:
+		logger.SetDefaultLevel(logger.DebugLevel)
+	case opts.quiet:
+		logger.SetDefaultLevel(logger.WarnLevel)
 	}
+	logger.Debug("Debug logging enabled")
+	return nil
+}
 
-	if len(inactiveRepos) == 0 {
-		logger.Info("No inactive repositories found.")
-		return
-	}
+// continuanceLimit is the number of error continuance messages logged with
+// -quiet-errors before the rest are suppressed
+const continuanceLimit = 5
 
-	logger.Info("%d repositories inactive for %d+ years:", len(inactiveRepos), *inactivityThreshold)
-	for _, repo := range inactiveRepos {
-		logger.Info("  - %s (Last activity: %s)", repo.Name, repo.LastActivity.Format("2006-01-02"))
-	}
+// continuances routes the error continuance messages of util.ForceProcessing
+// through the logger, optionally suppressing all but the first few
+type continuances struct {
+	mu         sync.Mutex
+	quiet      bool
+	seen       int
+	suppressed int
+}
 
-	// Stop here if this is a dry run
-	if *dryRun {
-		logger.Info("Dry run completed. No changes were made.")
+// log logs or suppresses a single continuance message
+func (c *continuances) log(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen++
+	if c.quiet && c.seen > continuanceLimit {
+		c.suppressed++
 		return
 	}
-
-	// 3. Archive inactive repositories
-	logger.Info("Archiving %d repositories:", len(inactiveRepos))
-	archiveNamespace := fmt.Sprintf("%s-archive", *target)
-
-	for i, repo := range inactiveRepos {
-		logger.Info("  - [%d/%d] Processing repository %s", i+1, len(inactiveRepos), repo.Name)
-
-		err := repoArchiver.ArchiveRepository(ctx, *target, archiveNamespace, repo.Name)
-		if util.ForceProcessing(err) {
-			logger.Error("Failed to archive repository %s: %v", repo.Name, err)
-			continue
-		}
-		logger.Info("  - [%d/%d] Successfully archived %s", i+1, len(inactiveRepos), repo.Name)
+	logger.Warn("Error continuance: %v", err)
+	if c.quiet && c.seen == continuanceLimit {
+		logger.Warn("Suppressing further error continuance messages")
 	}
+}
 
-	logger.Info("Archive process completed. %d repositories archived.", len(inactiveRepos))
+// report logs how many continuance messages were suppressed
+func (c *continuances) report() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.suppressed > 0 {
+		logger.Warn("%d further error continuance messages were suppressed (-quiet-errors)", c.suppressed)
+	}
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/archiver"
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/manifest"
+	"github.com/eyedeekay/github-archiver/pkg/report"
+)
+
+// checkNotices applies the notification flow to the candidates. It returns
+// those whose notice's grace period has elapsed without objection, and
+// report entries for the rest: candidates notified now, those still within
+// their grace period and those whose archiving was objected to. In a dry run
+// no notices are opened.
+func checkNotices(ctx context.Context, opts *options, repoArchiver *archiver.Archiver, manifestWriter *manifest.Writer, repos []github.Repository, grace time.Duration) ([]github.Repository, []report.Entry, error) {
+	var ready []github.Repository
+	var held []report.Entry
+	hold := func(repo github.Repository, status, signal string) {
+		held = append(held, report.Entry{
+			Owner:        repo.Owner,
+			Name:         repo.Name,
+			LastActivity: repo.LastActivity,
+			Status:       status,
+			Signal:       signal,
+			Score:        repo.Score,
+			SizeKB:       repo.Size,
+		})
+	}
+
+	for _, repo := range repos {
+		repoCtx, cancel := withRepoTimeout(ctx, opts.repoTimeout)
+		notice, err := repoArchiver.CheckNotice(repoCtx, repo, grace)
+		cancel()
+		if err != nil {
+			if errors.Is(err, github.ErrUnhealthy) || opts.onError == onErrorStop {
+				return nil, nil, fmt.Errorf("failed to check the notice of %s/%s: %w", repo.Owner, repo.Name, err)
+			}
+			logger.Error("Failed to check the notice of %s/%s: %v", repo.Owner, repo.Name, err)
+			hold(repo, report.StatusFailed, "")
+			continue
+		}
+
+		switch {
+		case notice.Objection != "":
+			logger.Info("Keeping %s/%s - archiving objected to in %s (%s)", repo.Owner, repo.Name, notice.Issue.URL, notice.Objection)
+			hold(repo, report.StatusObjected, notice.Objection)
+		case notice.Found && opts.clock.Now().Before(notice.GraceEnds):
+			logger.Info("Repository %s/%s was notified in %s, grace period ends %s",
+				repo.Owner, repo.Name, notice.Issue.URL, notice.GraceEnds.Format("2006-01-02"))
+			hold(repo, report.StatusNotified, "grace ends "+notice.GraceEnds.Format("2006-01-02"))
+		case notice.Found:
+			logger.Info("Grace period of %s/%s ended on %s without objection", repo.Owner, repo.Name, notice.GraceEnds.Format("2006-01-02"))
+			ready = append(ready, repo)
+		case opts.dryRun:
+			logger.Info("Would notify %s/%s of archiving", repo.Owner, repo.Name)
+			hold(repo, report.StatusNotified, "would notify")
+		default:
+			repoCtx, cancel := withRepoTimeout(ctx, opts.repoTimeout)
+			issue, err := repoArchiver.Notify(repoCtx, repo, grace)
+			cancel()
+			if err := manifestWriter.Write(notifyRecord(repo, err)); err != nil {
+				logger.Error("Failed to record %s in manifest: %v", repo.Name, err)
+			}
+			if err != nil {
+				if errors.Is(err, github.ErrUnhealthy) || opts.onError == onErrorStop {
+					return nil, nil, fmt.Errorf("failed to notify %s/%s: %w", repo.Owner, repo.Name, err)
+				}
+				logger.Error("Failed to notify %s/%s: %v", repo.Owner, repo.Name, err)
+				hold(repo, report.StatusFailed, "")
+				continue
+			}
+			logger.Info("Notified %s/%s in %s", repo.Owner, repo.Name, issue.URL)
+			hold(repo, report.StatusNotified, "grace ends "+opts.clock.Now().Add(grace).Format("2006-01-02"))
+		}
+	}
+	return ready, held, nil
+}
+
+// notifyRecord describes the outcome of notifying a repository
+func notifyRecord(repo github.Repository, err error) manifest.Record {
+	record := manifest.Record{
+		Owner:    repo.Owner,
+		Name:     repo.Name,
+		Strategy: "notify",
+		Status:   report.StatusNotified,
+	}
+	if err != nil {
+		record.Status = report.StatusFailed
+		record.Error = err.Error()
+	}
+	return record
+}
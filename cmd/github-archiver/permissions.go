@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/report"
+)
+
+// checkPermissions verifies that the token could archive or delete each
+// candidate that needs it, without changing anything. Both need admin
+// rights, which the listing reports; repositories listed without permissions
+// are fetched individually. Entries of repositories the action would fail on
+// are marked failed, and an error counts them.
+func checkPermissions(ctx context.Context, client *github.Client, action string, repos []github.Repository, entries []report.Entry, needed func(github.Repository) bool) error {
+	checked, denied := 0, 0
+	for _, repo := range repos {
+		if needed(repo) {
+			checked++
+		}
+	}
+	if checked == 0 {
+		logger.Info("Check: no original would be archived in place or deleted, admin permission is not needed")
+		return nil
+	}
+	logger.Info("Check: admin permission on %d repositories...", checked)
+	for i, repo := range repos {
+		if !needed(repo) {
+			logger.Debug("  - %s/%s: kept, admin permission not needed", repo.Owner, repo.Name)
+			continue
+		}
+		if !repo.HasPermissions {
+			fetched, err := client.GetRepository(ctx, repo.Owner, repo.Name)
+			if errors.Is(err, github.ErrUnhealthy) {
+				return err
+			}
+			if err != nil {
+				logger.Error("  - %s/%s: could not check permissions: %v", repo.Owner, repo.Name, err)
+				entries[i].Status = report.StatusFailed
+				entries[i].Signal = "permission check failed"
+				denied++
+				continue
+			}
+			repo = fetched
+		}
+		if !repo.Admin {
+			logger.Warn("  - %s/%s: no admin permission, %s would fail", repo.Owner, repo.Name, action)
+			entries[i].Status = report.StatusFailed
+			entries[i].Signal = "no admin permission"
+			denied++
+			continue
+		}
+		logger.Debug("  - %s/%s: ok", repo.Owner, repo.Name)
+	}
+
+	if denied > 0 {
+		return fmt.Errorf("%d of %d repositories lack the admin permission %s needs", denied, checked, action)
+	}
+	logger.Info("The token has admin permission on all %d repositories", checked)
+	return nil
+}
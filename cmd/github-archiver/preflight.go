@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eyedeekay/github-archiver/pkg/archiver"
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/report"
+)
+
+// dryRunChecks performs the read-only checks a real run depends on, so that
+// a clean dry run means archiving should succeed: the outcome of the archive
+// namespace or attic check made earlier, whether candidates to be forked
+// allow forking, and whether the token has the admin permission that archiving
+// and deleting need. Problems are logged and marked on the entries; nothing
+// is changed. Only an unhealthy API is returned as an error.
+func dryRunChecks(ctx context.Context, client *github.Client, opts *options, strategy archiver.Strategy, tiered bool, nsErr error, repos []github.Repository, entries []report.Entry) error {
+	passed := true
+	if nsErr != nil {
+		destination := "archive namespaces"
+		if strategy == archiver.StrategyAttic {
+			destination = "attic"
+		}
+		logger.Warn("Check: %s: %v", destination, nsErr)
+		passed = false
+	}
+
+	if strategy == archiver.StrategyFork {
+		logger.Info("Check: forking allowed on %d repositories...", len(repos))
+		for i, repo := range repos {
+			if repo.AllowForking || (tiered && !repo.DeleteTier) {
+				continue
+			}
+			logger.Warn("  - %s/%s: forking is disabled, it would be skipped", repo.Owner, repo.Name)
+			entries[i].Status = report.StatusForkDisabled
+			entries[i].Signal = "forking disabled"
+			passed = false
+		}
+	}
+
+	action := "deletion"
+	if strategy == archiver.StrategyArchive {
+		action = "archiving"
+	}
+	err := checkPermissions(ctx, client, action, repos, entries, func(repo github.Repository) bool {
+		return needsAdmin(opts, strategy, tiered, repo)
+	})
+	if errors.Is(err, github.ErrUnhealthy) {
+		return err
+	}
+	if err != nil {
+		logger.Warn("Check: %v", err)
+		passed = false
+	}
+
+	if passed {
+		logger.Info("Dry run checks passed: a real run should archive every candidate")
+	} else {
+		logger.Warn("Dry run checks failed: a real run would not archive every candidate")
+	}
+	return nil
+}
+
+// needsAdmin reports whether a real run would archive or delete the original
+// repository, which takes admin rights. The fork and attic strategies only
+// read originals they keep, and no strategy deletes a repository missing
+// the -require-topic. With a deletion tier, repositories below it are
+// archived in place.
+func needsAdmin(opts *options, strategy archiver.Strategy, tiered bool, repo github.Repository) bool {
+	approved := opts.requireTopic == "" || repo.HasTopic(opts.requireTopic)
+	switch {
+	case strategy == archiver.StrategyArchive || (tiered && !repo.DeleteTier):
+		return true
+	case strategy == archiver.StrategyFork || strategy == archiver.StrategyAttic:
+		return opts.allowDelete && approved
+	default:
+		return approved
+	}
+}
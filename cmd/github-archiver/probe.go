@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/analyzer"
+)
+
+// renderProbes writes the activity signals of each repository as a table,
+// with the latest activity and the signal it came from last
+func renderProbes(w io.Writer, probes []analyzer.Probe) error {
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tPUSHED\tUPDATED\tCREATED\tISSUE\tRELEASE\tWORKFLOW RUN\tLATEST\tSIGNAL")
+	for _, p := range probes {
+		a := p.Activity
+		latest, signal := a.Latest()
+		fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			p.Repository.Owner, p.Repository.Name,
+			date(a.Pushed), date(a.Updated), date(a.Created), date(a.Issue),
+			date(a.Release), date(a.WorkflowRun),
+			date(latest), signal)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write probe table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/archiver"
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// splitPending separates repositories already in quarantine, which must not
+// be analyzed since their notice commit counts as activity
+func splitPending(repos []github.Repository) ([]github.Repository, []github.Repository) {
+	var rest, pending []github.Repository
+	for _, repo := range repos {
+		if repo.HasTopic(archiver.QuarantineTopic) {
+			pending = append(pending, repo)
+		} else {
+			rest = append(rest, repo)
+		}
+	}
+	return rest, pending
+}
+
+// releaseQuarantined checks repositories in quarantine. It returns those to
+// process now and the keys of those whose quarantine has elapsed, which are
+// archived rather than quarantined. Repositories without a notice are
+// returned to be quarantined again.
+func releaseQuarantined(ctx context.Context, repoArchiver *archiver.Archiver, pending []github.Repository, period time.Duration, now time.Time) ([]github.Repository, map[string]bool, error) {
+	var selected []github.Repository
+	ready := make(map[string]bool)
+	for _, repo := range pending {
+		start, err := repoArchiver.QuarantineStart(ctx, repo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check quarantine of %s/%s: %w", repo.Owner, repo.Name, err)
+		}
+
+		switch until := start.Add(period); {
+		case start.IsZero():
+			logger.Warn("Repository %s/%s is tagged %s but has no %s, quarantining it again",
+				repo.Owner, repo.Name, archiver.QuarantineTopic, archiver.NoticeFile)
+		case now.Before(until):
+			logger.Info("Repository %s/%s is in quarantine until %s", repo.Owner, repo.Name, until.Format("2006-01-02"))
+			continue
+		default:
+			logger.Info("Quarantine of %s/%s ended on %s", repo.Owner, repo.Name, until.Format("2006-01-02"))
+			ready[repo.SortKey()] = true
+		}
+		repo.LastActivity = start
+		selected = append(selected, repo)
+	}
+	return selected, ready, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/analyzer"
+	"github.com/eyedeekay/github-archiver/pkg/archiver"
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// excludeArchived guards repeated runs against archiving their own
+// archives. With -exclude-recently-archived it drops repositories whose
+// archive copy already exists in their archive namespace; with
+// -exclude-archive-names it also drops repositories that look like archive
+// copies: those owned by an archive namespace or named with the configured
+// prefix or suffix.
+func excludeArchived(ctx context.Context, client *github.Client, repoArchiver *archiver.Archiver, opts *options, repos []github.Repository) ([]github.Repository, []analyzer.Spared, error) {
+	var copies map[string]map[string]bool
+	if opts.skipArchived {
+		copies = make(map[string]map[string]bool)
+		for _, ns := range archiveNamespaces(opts, repos) {
+			names, err := archivedNames(ctx, client, repoArchiver, ns)
+			if err != nil {
+				return nil, nil, err
+			}
+			copies[ns] = names
+		}
+	}
+
+	var kept []github.Repository
+	var skipped []analyzer.Spared
+	for _, repo := range repos {
+		ns := archiveNamespace(opts, repo)
+		copyName := opts.namePrefix + repo.Name + opts.nameSuffix
+		var signal string
+		switch {
+		case opts.skipArchiveNames && (strings.EqualFold(repo.Owner, ns) || strings.HasSuffix(strings.ToLower(repo.Owner), "-archive")):
+			signal = "owned by an archive namespace"
+		case opts.skipArchiveNames && isArchiveName(opts, repo.Name):
+			signal = "named like an archive copy"
+		case copies[ns][strings.ToLower(copyName)]:
+			signal = "already in " + ns
+		default:
+			kept = append(kept, repo)
+			continue
+		}
+		logger.Info("Skipping %s/%s - %s", repo.Owner, repo.Name, signal)
+		skipped = append(skipped, analyzer.Spared{Repository: repo, Signal: signal})
+	}
+	return kept, skipped, nil
+}
+
+// archivedNames lists the lowercased names of the repositories in an archive
+// namespace, none if it does not exist
+func archivedNames(ctx context.Context, client *github.Client, repoArchiver *archiver.Archiver, ns string) (map[string]bool, error) {
+	err := repoArchiver.VerifyNamespace(ctx, ns)
+	if errors.Is(err, github.ErrNamespaceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	org, err := client.IsOrganization(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := client.ListRepositories(ctx, ns, org, github.ListFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive namespace %s: %w", ns, err)
+	}
+	names := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		names[strings.ToLower(repo.Name)] = true
+	}
+	logger.Debug("Archive namespace %s holds %d repositories", ns, len(names))
+	return names, nil
+}
+
+// isArchiveName reports whether a name carries the configured archive
+// prefix or suffix
+func isArchiveName(opts *options, name string) bool {
+	name = strings.ToLower(name)
+	return (opts.namePrefix != "" && strings.HasPrefix(name, strings.ToLower(opts.namePrefix))) ||
+		(opts.nameSuffix != "" && strings.HasSuffix(name, strings.ToLower(opts.nameSuffix)))
+}
@@ -0,0 +1,1444 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/analyzer"
+	"github.com/eyedeekay/github-archiver/pkg/archiver"
+	"github.com/eyedeekay/github-archiver/pkg/events"
+	"github.com/eyedeekay/github-archiver/pkg/filter"
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/manifest"
+	"github.com/eyedeekay/github-archiver/pkg/metrics"
+	"github.com/eyedeekay/github-archiver/pkg/report"
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// run executes a full listing, analysis and archiving pass. Returned errors
+// are categorized so main can map them to exit codes.
+func run(ctx context.Context, opts *options) (err error) {
+	startedAt := time.Now()
+	util.FORCE_PROCESSING = opts.force
+	if err := configureLogging(opts); err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+	errorContinuances := &continuances{quiet: opts.quietErrors}
+	util.ContinuanceHook = errorContinuances.log
+	defer errorContinuances.report()
+
+	// Keep stdout clean for the machine-readable summary
+	switch opts.summaryFormat {
+	case summaryText:
+	case summaryJSON:
+		logger.SetDefaultOutput(os.Stderr)
+	default:
+		return util.Usagef("invalid -summary-format %q (valid: %s, %s)", opts.summaryFormat, summaryText, summaryJSON)
+	}
+
+	// Parse team scopes; the first team's organization is the default target
+	teams, err := parseTeams(opts.team)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+	if opts.target == "" && len(teams) > 0 {
+		opts.target = teams[0][0]
+	}
+
+	// Read a precomputed repository list, which names its own repositories
+	listMode := opts.archiveFrom != "" || opts.reposFromStdin
+	records, err := readInputRecords(opts)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+	if opts.target == "" && len(records) > 0 {
+		opts.target = records[0].Owner
+	}
+
+	// Validate required flags
+	if (opts.token == "" && opts.fixturesDir == "") || (opts.target == "" && !opts.allAdmin) {
+		return util.NewError(util.UsageError, errUsage)
+	}
+
+	if opts.teamDiscussion && len(teams) == 0 {
+		return util.Usagef("-report-team-discussion requires -team")
+	}
+
+	// Validate the report template before doing any work
+	var tmpl *template.Template
+	if opts.reportTemplate != "" {
+		tmpl, err = report.ParseTemplate(opts.reportTemplate)
+		if err != nil {
+			return util.NewError(util.UsageError, err)
+		}
+	}
+
+	if opts.allAdmin && (opts.org || opts.affiliation != "" || opts.team != "" || opts.search || listMode) {
+		return util.Usagef("-all-admin cannot be combined with -org, -affiliation, -team, -search, -archive-from or -repos-from-stdin")
+	}
+	if opts.compareNamespace != "" && (listMode || opts.allAdmin || opts.team != "") {
+		return util.Usagef("-compare-namespaces cannot be combined with -all-admin, -team, -archive-from or -repos-from-stdin")
+	}
+	if opts.affiliation != "" && opts.repoType != "" {
+		return util.Usagef("-affiliation and -repo-type cannot be combined")
+	}
+	if opts.affiliation != "" && opts.org {
+		return util.Usagef("-affiliation only applies to the authenticated user, not organizations")
+	}
+
+	if opts.maxCommits < 0 || opts.minWatchers < 0 {
+		return util.Usagef("-max-commits and -min-watchers must not be negative")
+	}
+
+	if opts.interactive && opts.reposFromStdin {
+		return util.Usagef("-interactive cannot be combined with -repos-from-stdin")
+	}
+
+	weights := analyzer.Weights{
+		Recency: opts.weightRecency,
+		Stars:   opts.weightStars,
+		Issues:  opts.weightIssues,
+		Size:    opts.weightSize,
+	}
+	if opts.scoreThreshold < 0 || opts.scoreThreshold > 100 {
+		return util.Usagef("-score-threshold must be between 0 and 100")
+	}
+	if err := weights.Validate(); opts.scoreThreshold > 0 && err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+
+	if opts.repoTimeout < 0 {
+		return util.Usagef("-repo-timeout must not be negative")
+	}
+	if opts.maxRuntime < 0 {
+		return util.Usagef("-max-runtime must not be negative")
+	}
+	if opts.deferredFile != "" && opts.maxRuntime == 0 {
+		return util.Usagef("-deferred-file requires -max-runtime")
+	}
+
+	// Bound the whole run; archiving finishes the repository in flight and
+	// keeps working under the parent context
+	parent := ctx
+	if opts.maxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, startedAt.Add(opts.maxRuntime))
+		defer cancel()
+	}
+	if opts.batchSize < 0 || opts.batchPause < 0 {
+		return util.Usagef("-batch-size and -batch-pause must not be negative")
+	}
+
+	if opts.onError != onErrorContinue && opts.onError != onErrorStop {
+		return util.Usagef("invalid -on-error %q (valid: %s, %s)", opts.onError, onErrorContinue, onErrorStop)
+	}
+
+	reporters, err := buildReporters(opts, tmpl)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+
+	if opts.sandbox != "" && opts.dryRun {
+		return util.Usagef("-dry-run-archive and -dry-run cannot be combined")
+	}
+	if opts.deleteCheck && !opts.dryRun {
+		return util.Usagef("-dry-run-delete-check requires -dry-run")
+	}
+	if opts.sandboxCleanup && opts.sandbox == "" {
+		return util.Usagef("-dry-run-archive-cleanup requires -dry-run-archive")
+	}
+
+	// Resolve the inactivity range
+	inactivityPeriod, maxInactivity, err := inactivityRange(opts)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+
+	// Resolve the shorter threshold of generated repositories
+	var generatedPeriod time.Duration
+	generatedRules := analyzer.GeneratedRules{
+		Names:     splitList(opts.generatedNames),
+		Languages: splitList(opts.generatedLangs),
+	}
+	if opts.generatedAfter != "" {
+		generatedPeriod, err = util.ParseDuration(opts.generatedAfter)
+		if err != nil {
+			return util.Usagef("invalid -generated-threshold: %v", err)
+		}
+		if generatedPeriod >= inactivityPeriod {
+			return util.Usagef("-generated-threshold (%v) must be less than the inactivity threshold (%v)", generatedPeriod, inactivityPeriod)
+		}
+		if err := generatedRules.Validate(); err != nil {
+			return util.NewError(util.UsageError, err)
+		}
+	}
+
+	// Resolve the shorter threshold of repositories abandoned by the organization
+	var departedPeriod time.Duration
+	if opts.departedAfter != "" {
+		departedPeriod, err = util.ParseDuration(opts.departedAfter)
+		if err != nil {
+			return util.Usagef("invalid -departed-threshold: %v", err)
+		}
+		if departedPeriod >= inactivityPeriod {
+			return util.Usagef("-departed-threshold (%v) must be less than the inactivity threshold (%v)", departedPeriod, inactivityPeriod)
+		}
+		if !opts.org && opts.team == "" {
+			return util.Usagef("-departed-threshold requires -org or -team")
+		}
+	}
+
+	// Resolve the generational retention policy
+	var groupPattern *regexp.Regexp
+	if opts.keepLatest < 0 {
+		return util.Usagef("-keep-latest-n must not be negative")
+	}
+	if opts.keepLatest > 0 {
+		if listMode {
+			return util.Usagef("-keep-latest-n cannot be combined with -archive-from or -repos-from-stdin")
+		}
+		groupPattern, err = regexp.Compile(opts.groupRegex)
+		if err != nil {
+			return util.Usagef("invalid -group-regex: %v", err)
+		}
+	}
+
+	// Resolve the base timestamp for last activity
+	thresholdSource, err := github.ParseThresholdSource(opts.thresholdSrc)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+	if opts.probeOnly && (listMode || opts.search) {
+		return util.Usagef("-probe-only cannot be combined with -search, -archive-from or -repos-from-stdin")
+	}
+	if opts.noDescription && opts.described {
+		return util.Usagef("-only-no-description and -only-described cannot be combined")
+	}
+	if opts.onlyEmpty && (listMode || opts.search) {
+		return util.Usagef("-only-empty cannot be combined with -search, -archive-from or -repos-from-stdin")
+	}
+	if opts.search && thresholdSource == github.SourceCreated {
+		return util.Usagef("-search pre-filters by push date and cannot be used with -threshold-source=created")
+	}
+
+	// Resolve the quarantine period of the two-phase flow
+	var quarantine time.Duration
+	if opts.quarantine != "" {
+		quarantine, err = util.ParseDuration(opts.quarantine)
+		if err != nil {
+			return util.Usagef("invalid -quarantine: %v", err)
+		}
+		if opts.sandbox != "" {
+			return util.Usagef("-quarantine and -dry-run-archive cannot be combined")
+		}
+	}
+
+	// Resolve the grace period of the notification flow
+	var notifyGrace time.Duration
+	if opts.notifyGrace != "" {
+		notifyGrace, err = util.ParseDuration(opts.notifyGrace)
+		if err != nil {
+			return util.Usagef("invalid -notify-grace: %v", err)
+		}
+		if quarantine > 0 {
+			return util.Usagef("-notify-grace and -quarantine cannot be combined")
+		}
+	}
+
+	namespaceType, err := github.ParseNamespaceType(opts.namespaceType)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+
+	// Resolve the archive strategy; destructive strategies need an explicit opt-in
+	strategy, err := archiver.ParseStrategy(opts.strategy)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+	if strategy.RequiresDelete() && !opts.allowDelete {
+		return util.NewError(util.UsageError, archiver.ErrDeleteNotAllowed)
+	}
+
+	if opts.protectBranch && strategy != archiver.StrategyFork {
+		return util.Usagef("-protect-archive-branch requires -strategy=fork")
+	}
+	if opts.verifySigs && strategy != archiver.StrategyFork {
+		return util.Usagef("-verify-signatures requires -strategy=fork")
+	}
+	if opts.archivePrivate && strategy != archiver.StrategyFork {
+		return util.Usagef("-archive-private requires -strategy=fork")
+	}
+	if opts.copyCollabs && strategy != archiver.StrategyFork {
+		return util.Usagef("-copy-collaborators requires -strategy=fork")
+	}
+	var atticOwner, atticName string
+	if strategy == archiver.StrategyAttic {
+		var ok bool
+		atticOwner, atticName, ok = strings.Cut(opts.atticRepo, "/")
+		if !ok || atticOwner == "" || atticName == "" || strings.Contains(atticName, "/") {
+			return util.Usagef("-strategy=attic requires -attic-repo in the form owner/name")
+		}
+		// git cannot reach fixtures, so only a dry run can simulate the attic
+		if opts.fixturesDir != "" && !opts.dryRun {
+			return util.Usagef("-strategy=attic cannot be used with -fixtures-dir outside a -dry-run")
+		}
+	} else if opts.atticRepo != "" || opts.atticShallow {
+		return util.Usagef("-attic-repo and -attic-shallow require -strategy=attic")
+	}
+	if opts.deleteForks && !opts.allowDelete {
+		return util.Usagef("-delete-active-forks requires -allow-delete")
+	}
+
+	// Resolve the longer threshold of the destructive tier
+	var deletePeriod time.Duration
+	if opts.deleteAfter != "" {
+		deletePeriod, err = util.ParseDuration(opts.deleteAfter)
+		if err != nil {
+			return util.Usagef("invalid -min-inactivity-for-delete: %v", err)
+		}
+		switch {
+		case deletePeriod <= inactivityPeriod:
+			return util.Usagef("-min-inactivity-for-delete (%v) must be greater than the inactivity threshold (%v)", deletePeriod, inactivityPeriod)
+		case maxInactivity > 0 && deletePeriod > maxInactivity:
+			return util.Usagef("-min-inactivity-for-delete (%v) must not be greater than -max-inactivity (%v)", deletePeriod, maxInactivity)
+		case strategy == archiver.StrategyArchive:
+			return util.Usagef("-min-inactivity-for-delete needs a destructive -strategy (fork or delete)")
+		case !opts.allowDelete:
+			return util.NewError(util.UsageError, archiver.ErrDeleteNotAllowed)
+		case listMode || opts.onlyEmpty || quarantine > 0:
+			return util.Usagef("-min-inactivity-for-delete cannot be combined with -archive-from, -repos-from-stdin, -only-empty or -quarantine")
+		}
+	}
+
+	// Read the time of the last successful run, recording this one once a
+	// real run completes. Dry runs and listings change nothing, so what they
+	// saw must still be analyzed next time.
+	var activeSince time.Time
+	var completed bool
+	if opts.sinceFile != "" {
+		activeSince, err = readSinceFile(opts.sinceFile)
+		if err != nil {
+			return util.NewError(util.UsageError, err)
+		}
+		defer func() {
+			if err != nil || !completed || opts.dryRun || opts.listNamespaces || opts.sandbox != "" {
+				return
+			}
+			if werr := writeSinceFile(opts.sinceFile, startedAt); werr != nil {
+				logger.Error("%v", werr)
+			}
+		}()
+	}
+
+	// Open the machine-readable event stream
+	closeEvents, err := openEvents(opts)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+	defer closeEvents()
+
+	// Load exclude lists up front so an unreachable source fails fast. They
+	// keep repositories safe, so even -force never runs without them.
+	repoFilter, err := regexFilter(opts)
+	if err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+	if err := loadExcludes(ctx, repoFilter, opts); err != nil {
+		return util.NewError(util.UsageError, err)
+	}
+
+	// Open the manifest of processed repositories
+	var manifestWriter *manifest.Writer
+	if opts.manifestPath != "" {
+		manifestWriter, err = manifest.Open(opts.manifestPath)
+		if err != nil {
+			return util.NewError(util.UsageError, err)
+		}
+		defer manifestWriter.Close()
+	}
+
+	// Initialize GitHub client
+	logger.Debug("Initializing GitHub client")
+	var client *github.Client
+	if opts.fixturesDir != "" {
+		client, err = github.NewFixtureClient(opts.fixturesDir, opts.userAgent)
+		if err != nil {
+			return util.NewError(util.UsageError, err)
+		}
+	} else {
+		client, err = github.NewClient(ctx, opts.token, opts.userAgent)
+	}
+	if util.ForceProcessing(err) {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	client.SetRetryPolicy(opts.retryBudget, opts.breakerLimit)
+
+	// Hold the token's user accountable for every change the run makes
+	if opts.auditLog != "" {
+		auditor, err := openAuditor(opts.auditLog)
+		if err != nil {
+			return util.NewError(util.UsageError, err)
+		}
+		defer auditor.Close()
+		user, err := client.AuthenticatedUser(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to identify the token's user for the audit log: %w", err)
+		}
+		client.SetAuditor(user, auditor)
+		logger.Info("Auditing changes made as %s to %s", user, opts.auditLog)
+	}
+
+	// Audit an earlier migration instead of archiving
+	if opts.compareNamespace != "" {
+		return compareNamespaces(ctx, client, opts, reporters)
+	}
+
+	// Post the summary back to the teams the run was scoped to
+	if opts.teamDiscussion {
+		if opts.dryRun {
+			logger.Info("Dry run: the summary will not be posted to team discussions")
+		} else {
+			for _, t := range teams {
+				org, slug := t[0], t[1]
+				reporters = append(reporters, report.NewDiscussionReporter(func(ctx context.Context, title, body string) error {
+					return client.CreateTeamDiscussion(ctx, org, slug, title, body)
+				}))
+			}
+		}
+	}
+
+	// Repositories across owners are listed for the token's user
+	if opts.allAdmin && opts.target == "" {
+		opts.target, err = client.AuthenticatedUser(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	client.SetMaxRPS(opts.maxRPS)
+	client.SetNamespaceType(namespaceType)
+	defer logRateUsage(client)
+
+	// Collect run statistics from every phase
+	counters := metrics.New()
+	client.SetMetrics(counters)
+	defer func() { printSummary(opts, counters, startedAt, err) }()
+
+	// Repositories that fail without stopping the run fail it once done
+	var failures util.MultiError
+	defer func() {
+		if err == nil {
+			err = failures.ErrorOrNil()
+		}
+	}()
+
+	// Create the repository analyzer
+	repoAnalyzer := analyzer.NewAnalyzer(client, inactivityPeriod)
+	repoAnalyzer.SetClock(opts.clock)
+	repoAnalyzer.SetMaxInactivity(maxInactivity)
+	repoAnalyzer.SetDeleteThreshold(deletePeriod)
+	repoAnalyzer.SetThresholdSource(thresholdSource)
+	repoAnalyzer.SetCheckOpenPullRequests(opts.checkPulls)
+	repoAnalyzer.SetSkipTemplates(opts.skipTemplates)
+	repoAnalyzer.SetSkipMirrors(opts.skipMirrors)
+	repoAnalyzer.SetCheckWorkflows(opts.checkWorkflows)
+	repoAnalyzer.SetCheckSecurity(opts.checkSecurity)
+	repoAnalyzer.SetMaxCommits(opts.maxCommits)
+	repoAnalyzer.SetMinWatchers(opts.minWatchers)
+	repoAnalyzer.SetSkipProtected(opts.skipProtected)
+	repoAnalyzer.SetScoring(weights, opts.scoreThreshold)
+	repoAnalyzer.SetGenerated(generatedRules, generatedPeriod)
+	repoAnalyzer.SetDepartedContributors(departedPeriod)
+	repoAnalyzer.SetRecordSpared(opts.reportActive)
+	repoAnalyzer.SetActiveSince(activeSince)
+	repoAnalyzer.SetMetrics(counters)
+	repoAnalyzer.SetContinueOnError(opts.onError == onErrorContinue)
+	logger.Debug("Repository analyzer initialized with %v threshold", inactivityPeriod)
+
+	// Create the repository archiver
+	repoArchiver := archiver.NewArchiver(client)
+	repoArchiver.SetClock(opts.clock)
+	repoArchiver.SetStrategy(strategy)
+	repoArchiver.SetAllowDelete(opts.allowDelete)
+	repoArchiver.SetForkTimeout(opts.forkTimeout, opts.forkTimeoutMax)
+	repoArchiver.SetArchiveName(opts.namePrefix, opts.nameSuffix)
+	repoArchiver.SetMetrics(counters)
+	repoArchiver.SetRequiredTopic(opts.requireTopic)
+	repoArchiver.SetObjectionLabel(opts.objectionLabel)
+	repoArchiver.SetTiered(deletePeriod > 0)
+	repoArchiver.SetProtectBranch(opts.protectBranch)
+	repoArchiver.SetVerifySignatures(opts.verifySigs)
+	repoArchiver.SetArchivePrivate(opts.archivePrivate)
+	repoArchiver.SetCopyCollaborators(opts.copyCollabs)
+	repoArchiver.SetAttic(atticOwner, atticName, opts.atticShallow, opts.token)
+	if opts.deleteForks {
+		repoArchiver.SetDeleteActiveForks(inactivityPeriod)
+	}
+	logger.Debug("Repository archiver initialized with %s strategy", strategy)
+
+	var repos, inactiveRepos, pending []github.Repository
+	var owned int // repositories listed for the owner, before any filtering
+	var spared, review []analyzer.Spared
+	counters.SetPhase("listing")
+	if listMode {
+		// Archive a precomputed list, skipping listing and analysis
+		logger.Info("Validating %d listed repositories...", len(records))
+		inactiveRepos, err = validateRecords(ctx, client, records)
+		if err != nil {
+			return err
+		}
+		inactiveRepos = repoFilter.Apply(inactiveRepos)
+		inactiveRepos = resumeFrom(inactiveRepos, opts.resumeFrom)
+		if opts.skipArchived || opts.skipArchiveNames {
+			var skipped []analyzer.Spared
+			inactiveRepos, skipped, err = excludeArchived(ctx, client, repoArchiver, opts, inactiveRepos)
+			if err != nil {
+				return err
+			}
+			spared = append(spared, skipped...)
+		}
+		if opts.noDescription || opts.described {
+			var skipped []analyzer.Spared
+			inactiveRepos, skipped = analyzer.FilterDescription(inactiveRepos, opts.described)
+			spared = append(spared, skipped...)
+		}
+		counters.Add(metrics.Total, int64(len(records)))
+		counters.Add(metrics.Skipped, int64(len(records)-len(inactiveRepos)))
+		counters.Add(metrics.Inactive, int64(len(inactiveRepos)))
+		if quarantine > 0 {
+			inactiveRepos, pending = splitPending(inactiveRepos)
+		}
+	} else {
+		// 1. Fetch all repositories for the target, or for the requested teams
+		var searched bool
+		repos, searched, err = listRepositories(ctx, client, opts, teams, inactivityPeriod)
+		if err != nil {
+			return err
+		}
+		logger.Info("Found %d repositories for %s", len(repos), opts.target)
+		for _, repo := range repos {
+			events.Emit(events.Event{Type: events.RepoListed, Owner: repo.Owner, Repo: repo.Name})
+		}
+		listed := len(repos)
+		if searched {
+			logger.Info("Search returned only candidates, so -max-archive-fraction cannot be checked")
+		} else {
+			owned = listed
+		}
+		repos = repoFilter.Apply(repos)
+		repos = resumeFrom(repos, opts.resumeFrom)
+		counters.Add(metrics.Total, int64(listed))
+		counters.Add(metrics.Skipped, int64(listed-len(repos)))
+
+		// Never archive the tool's own archives again
+		if opts.skipArchived || opts.skipArchiveNames {
+			var skipped []analyzer.Spared
+			repos, skipped, err = excludeArchived(ctx, client, repoArchiver, opts, repos)
+			if err != nil {
+				return err
+			}
+			counters.Add(metrics.Skipped, int64(len(skipped)))
+			spared = append(spared, skipped...)
+		}
+
+		// Narrow the candidates by whether they have a description
+		if opts.noDescription || opts.described {
+			var skipped []analyzer.Spared
+			repos, skipped = analyzer.FilterDescription(repos, opts.described)
+			counters.Add(metrics.Skipped, int64(len(skipped)))
+			spared = append(spared, skipped...)
+		}
+
+		// Show the signal breakdown instead of selecting anything
+		if opts.probeOnly {
+			probes, err := repoAnalyzer.ProbeRepositories(ctx, repos)
+			if err != nil {
+				return err
+			}
+			return renderProbes(os.Stdout, probes)
+		}
+
+		// Keep the latest generations of each family of repositories
+		if opts.keepLatest > 0 {
+			var kept []analyzer.Spared
+			repos, kept = analyzer.KeepLatest(repos, groupPattern, opts.keepLatest, thresholdSource)
+			counters.Add(metrics.Skipped, int64(len(kept)))
+			spared = append(spared, kept...)
+		}
+		if quarantine > 0 {
+			repos, pending = splitPending(repos)
+		}
+
+		// 2. Analyze repositories for inactivity, or for emptiness
+		counters.SetPhase("analysis")
+		analyze := repoAnalyzer.FindInactiveRepositories
+		if opts.onlyEmpty {
+			logger.Info("Looking for empty repositories...")
+			analyze = repoAnalyzer.FindEmptyRepositories
+		} else {
+			logger.Info("Analyzing repository activity...")
+		}
+		chunks := batches(repos, opts.batchSize)
+		for n, chunk := range chunks {
+			if len(chunks) > 1 {
+				logger.Info("Analyzing batch %d/%d (%d repositories)...", n+1, len(chunks), len(chunk))
+			}
+			found, err := analyze(ctx, chunk)
+			if errors.Is(err, github.ErrUnhealthy) {
+				return err
+			}
+			if util.ForceProcessing(err) {
+				events.Emit(events.Event{Type: events.Error, Message: err.Error()})
+				return fmt.Errorf("failed to analyze repositories: %w", err)
+			}
+			inactiveRepos = append(inactiveRepos, found...)
+			failures.Add(repoAnalyzer.Failures())
+			spared = append(spared, repoAnalyzer.Spared()...)
+			review = append(review, repoAnalyzer.Review()...)
+			if n < len(chunks)-1 {
+				logger.Info("Batch %d/%d analyzed: %d of %d repositories selected so far",
+					n+1, len(chunks), len(inactiveRepos), len(repos))
+				if err := pauseBatch(ctx, opts.batchPause); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Candidates are only known once analysis completes
+	if pastDeadline(ctx) {
+		return fmt.Errorf("-max-runtime of %v ran out before analysis finished: %w", opts.maxRuntime, ctx.Err())
+	}
+
+	// Bring back repositories whose quarantine has elapsed
+	var released map[string]bool
+	if len(pending) > 0 {
+		var selected []github.Repository
+		selected, released, err = releaseQuarantined(ctx, repoArchiver, pending, quarantine, opts.clock.Now())
+		if err != nil {
+			return err
+		}
+		inactiveRepos = append(inactiveRepos, selected...)
+		github.SortRepositories(inactiveRepos)
+	}
+
+	// Report the repositories analysis kept, and why
+	var sparedEntries []report.Entry
+	if opts.reportActive {
+		for _, s := range spared {
+			sparedEntries = append(sparedEntries, report.Entry{
+				Owner:        s.Repository.Owner,
+				Name:         s.Repository.Name,
+				LastActivity: s.Repository.LastActivity,
+				Status:       report.StatusActive,
+				Signal:       s.Signal,
+				Score:        s.Repository.Score,
+				SizeKB:       s.Repository.Size,
+			})
+		}
+	}
+
+	// Flag repositories whose timestamps cannot be trusted
+	if len(review) > 0 {
+		logger.Warn("%d repositories have implausible activity timestamps and need manual review:", len(review))
+		for _, s := range review {
+			logger.Warn("  - %s/%s: %s", s.Repository.Owner, s.Repository.Name, s.Signal)
+			sparedEntries = append(sparedEntries, report.Entry{
+				Owner:        s.Repository.Owner,
+				Name:         s.Repository.Name,
+				LastActivity: s.Repository.LastActivity,
+				Status:       report.StatusReview,
+				Signal:       s.Signal,
+				SizeKB:       s.Repository.Size,
+			})
+		}
+	}
+
+	if len(inactiveRepos) == 0 {
+		logger.Info("No inactive repositories found.")
+		if opts.reportActive || len(review) > 0 {
+			renderReport(ctx, reporters, sparedEntries)
+		}
+		completed = true
+		return nil
+	}
+
+	// Let the user deselect candidates to keep
+	if opts.interactive {
+		if !isTerminal() {
+			logger.Warn("Not running in a terminal; -interactive ignored, keeping all %d candidates", len(inactiveRepos))
+		} else {
+			var deselected []github.Repository
+			inactiveRepos, deselected, err = selectInteractively(os.Stdin, os.Stdout, inactiveRepos, opts.clock.Now())
+			if errors.Is(err, errSelectionAborted) {
+				logger.Info("Selection aborted. No changes were made.")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			counters.Add(metrics.Skipped, int64(len(deselected)))
+			for _, repo := range deselected {
+				logger.Info("Keeping %s/%s - deselected", repo.Owner, repo.Name)
+				if opts.reportActive {
+					sparedEntries = append(sparedEntries, report.Entry{
+						Owner:        repo.Owner,
+						Name:         repo.Name,
+						LastActivity: repo.LastActivity,
+						Status:       report.StatusActive,
+						Signal:       "deselected",
+					})
+				}
+			}
+			if len(inactiveRepos) == 0 {
+				logger.Info("No repositories selected. No changes were made.")
+				if opts.reportActive {
+					renderReport(ctx, reporters, sparedEntries)
+				}
+				completed = true
+				return nil
+			}
+		}
+	}
+
+	// Only archive candidates notified long enough ago without objection
+	if notifyGrace > 0 {
+		var held []report.Entry
+		inactiveRepos, held, err = checkNotices(ctx, opts, repoArchiver, manifestWriter, inactiveRepos, notifyGrace)
+		if err != nil {
+			return err
+		}
+		counters.Add(metrics.Skipped, int64(len(held)))
+		sparedEntries = append(sparedEntries, held...)
+		if len(inactiveRepos) == 0 {
+			logger.Info("No notified repositories are ready to archive.")
+			renderReport(ctx, reporters, sparedEntries)
+			completed = true
+			return nil
+		}
+	}
+
+	switch {
+	case listMode:
+		logger.Info("%d listed repositories selected:", len(inactiveRepos))
+	case opts.onlyEmpty:
+		logger.Info("%d empty repositories:", len(inactiveRepos))
+	default:
+		logger.Info("%d repositories inactive for %s:", len(inactiveRepos), describeRange(inactivityPeriod, maxInactivity))
+	}
+	entries := make([]report.Entry, 0, len(inactiveRepos)+len(sparedEntries))
+	deleteTier := 0
+	for _, repo := range inactiveRepos {
+		if deletePeriod > 0 {
+			tier := "archive in place"
+			if repo.DeleteTier {
+				tier = string(strategy)
+				deleteTier++
+			}
+			logger.Info("  - %s (Last activity: %s, %s)", repo.Name, repo.LastActivity.Format("2006-01-02"), tier)
+		} else {
+			logger.Info("  - %s (Last activity: %s)", repo.Name, repo.LastActivity.Format("2006-01-02"))
+		}
+		entries = append(entries, report.Entry{
+			Owner:        repo.Owner,
+			Name:         repo.Name,
+			LastActivity: repo.LastActivity,
+			Status:       report.StatusInactive,
+			Signal:       repo.ActivitySignal,
+			Score:        repo.Score,
+			SizeKB:       repo.Size,
+		})
+	}
+	// Archiving updates entries by index, so spared ones go last
+	entries = append(entries, sparedEntries...)
+	if deletePeriod > 0 {
+		logger.Info("%d repositories inactive for %s get the %s strategy; %d are archived in place",
+			deleteTier, describeRange(deletePeriod, maxInactivity), strategy, len(inactiveRepos)-deleteTier)
+	}
+
+	// Guard against a misconfiguration flagging most of the account
+	if err := checkArchiveLimits(opts, len(inactiveRepos), owned); err != nil {
+		if !opts.dryRun && opts.sandbox == "" {
+			return err
+		}
+		logger.Warn("%v", err)
+	}
+
+	// Check every archive namespace, or the attic, up front rather than
+	// failing mid-run
+	var nsErr error
+	if strategy == archiver.StrategyFork || opts.sandbox != "" {
+		namespaces := archiveNamespaces(opts, inactiveRepos)
+		nsErr = checkNamespaces(ctx, repoArchiver, namespaces)
+		if opts.listNamespaces {
+			return nsErr
+		}
+		// A missing namespace fails every repository, even under -force
+		if nsErr != nil && !opts.dryRun && (errors.Is(nsErr, github.ErrNamespaceNotFound) || util.ForceProcessing(nsErr)) {
+			return nsErr
+		}
+	} else if opts.listNamespaces {
+		logger.Info("The %s strategy does not use an archive namespace", strategy)
+		return nil
+	} else if strategy == archiver.StrategyAttic {
+		// A missing attic fails every repository, even under -force
+		nsErr = repoArchiver.VerifyAttic(ctx)
+		if nsErr != nil && !opts.dryRun {
+			return nsErr
+		}
+	}
+
+	// Stop here if this is a dry run, after checking what the real run needs
+	if opts.dryRun {
+		if err := dryRunChecks(ctx, client, opts, strategy, deletePeriod > 0, nsErr, inactiveRepos, entries); err != nil {
+			return err
+		}
+		renderReport(ctx, reporters, entries)
+		logger.Info("Dry run completed. No changes were made.")
+		return nil
+	}
+
+	// Fork into the sandbox instead of archiving
+	if opts.sandbox != "" {
+		return testArchive(ctx, opts, reporters, repoArchiver, inactiveRepos, entries)
+	}
+
+	// 3. Archive inactive repositories
+	counters.SetPhase("archiving")
+	logger.Info("Archiving %d repositories:", len(inactiveRepos))
+	archived, quarantined := 0, 0
+	deadline := ctx
+	ctx = parent
+	for i, repo := range inactiveRepos {
+		if err := nextBatch(deadline, opts, manifestWriter, inactiveRepos, i); err != nil && !pastDeadline(deadline) {
+			renderReport(ctx, reporters, entries)
+			return err
+		}
+		if pastDeadline(deadline) {
+			counters.Add(metrics.Deferred, int64(len(inactiveRepos)-i))
+			deferRepositories(opts, strategy, inactiveRepos[i:], entries[i:])
+			break
+		}
+		logger.Info("  - [%d/%d] Processing repository %s", i+1, len(inactiveRepos), repo.Name)
+
+		// Quarantine new candidates instead of archiving them
+		if quarantine > 0 && !released[repo.SortKey()] {
+			repoCtx, cancel := withRepoTimeout(ctx, opts.repoTimeout)
+			err := repoArchiver.Quarantine(repoCtx, repo, quarantine)
+			cancel()
+			if err := manifestWriter.Write(quarantineRecord(repo, err)); err != nil {
+				logger.Error("Failed to record %s in manifest: %v", repo.Name, err)
+			}
+			if errors.Is(err, github.ErrUnhealthy) {
+				renderReport(ctx, reporters, entries)
+				return err
+			}
+			if err != nil {
+				logger.Error("Failed to quarantine repository %s: %v", repo.Name, err)
+				failures.Add(fmt.Errorf("failed to quarantine %s/%s: %w", repo.Owner, repo.Name, err))
+				entries[i].Status = report.StatusFailed
+				if opts.onError == onErrorStop {
+					renderReport(ctx, reporters, entries)
+					return fmt.Errorf("stopping after failure to quarantine %s/%s: %w", repo.Owner, repo.Name, err)
+				}
+				continue
+			}
+			entries[i].Status = report.StatusQuarantined
+			quarantined++
+			continue
+		}
+
+		// Preserve discussion context before the original can be deleted.
+		// -repo-timeout bounds the whole sequence.
+		repoCtx, cancel := withRepoTimeout(ctx, opts.repoTimeout)
+		var gistURL, settingsFile string
+		var result archiver.Result
+		var err error
+		if opts.exportGist {
+			gistURL, err = repoArchiver.ExportIssues(repoCtx, repo)
+		}
+		if err == nil && opts.exportSettings != "" {
+			settingsFile, err = repoArchiver.ExportSettings(repoCtx, repo, opts.exportSettings)
+		}
+		if err == nil {
+			result, err = repoArchiver.ArchiveRepository(repoCtx, archiveNamespace(opts, repo), repo)
+		}
+		timedOut := err != nil && errors.Is(repoCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+		cancel()
+		record := manifestRecord(repo, result, err)
+		record.GistURL = gistURL
+		record.SettingsFile = settingsFile
+		if timedOut {
+			record.Status = report.StatusTimedOut
+		}
+		if err := manifestWriter.Write(record); err != nil {
+			logger.Error("Failed to record %s in manifest: %v", repo.Name, err)
+		}
+		if errors.Is(err, github.ErrUnhealthy) {
+			renderReport(ctx, reporters, entries)
+			return err
+		}
+		if timedOut {
+			logger.Error("Abandoned repository %s after the %v timeout: %v", repo.Name, opts.repoTimeout, err)
+			counters.Inc(metrics.TimedOut)
+			failures.Add(fmt.Errorf("timed out archiving %s/%s: %w", repo.Owner, repo.Name, err))
+			entries[i].Status = report.StatusTimedOut
+			if opts.onError == onErrorStop {
+				renderReport(ctx, reporters, entries)
+				return fmt.Errorf("stopping after timeout archiving %s/%s: %w", repo.Owner, repo.Name, err)
+			}
+			continue
+		}
+		if errors.Is(err, github.ErrForkDisabled) {
+			logger.Warn("  - [%d/%d] Skipped %s: forking is disabled", i+1, len(inactiveRepos), repo.Name)
+			entries[i].Status = report.StatusForkDisabled
+			continue
+		}
+		if errors.Is(err, archiver.ErrMissingTopic) {
+			logger.Warn("  - [%d/%d] Skipped %s: missing the required topic %q", i+1, len(inactiveRepos), repo.Name, opts.requireTopic)
+			entries[i].Status = report.StatusMissingTopic
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to archive repository %s: %v", repo.Name, err)
+			events.Emit(events.Event{Type: events.Error, Owner: repo.Owner, Repo: repo.Name, Message: err.Error()})
+			failures.Add(fmt.Errorf("failed to archive %s/%s: %w", repo.Owner, repo.Name, err))
+			entries[i].Status = report.StatusFailed
+			if errors.Is(err, archiver.ErrSignatureMismatch) {
+				entries[i].Status = report.StatusSignatureMismatch
+			}
+			if opts.onError == onErrorStop {
+				renderReport(ctx, reporters, entries)
+				return fmt.Errorf("stopping after failure to archive %s/%s: %w", repo.Owner, repo.Name, err)
+			}
+			continue
+		}
+		entries[i].Status = report.StatusArchived
+		events.Emit(events.Event{
+			Type:  events.RepoArchived,
+			Owner: repo.Owner,
+			Repo:  repo.Name,
+			Data: map[string]interface{}{
+				"namespace":     result.Namespace,
+				"archived_name": result.ArchivedName,
+				"strategy":      string(result.Strategy),
+			},
+		})
+		archived++
+		logger.Info("  - [%d/%d] Successfully archived %s", i+1, len(inactiveRepos), repo.Name)
+	}
+
+	renderReport(ctx, reporters, entries)
+	if quarantined > 0 {
+		logger.Info("%d repositories quarantined for %v.", quarantined, quarantine)
+	}
+	logger.Info("Archive process completed. %d repositories archived.", archived)
+	completed = true
+	return nil
+}
+
+// testArchive forks each candidate into the sandbox namespace to validate
+// permissions and the fork flow. Original repositories are never modified.
+func testArchive(ctx context.Context, opts *options, reporters []report.Reporter, repoArchiver *archiver.Archiver, repos []github.Repository, entries []report.Entry) error {
+	logger.Info("Test forking %d repositories into sandbox %s:", len(repos), opts.sandbox)
+
+	tested := 0
+	for i, repo := range repos {
+		testName, err := repoArchiver.TestFork(ctx, opts.sandbox, repo, opts.sandboxCleanup)
+		if errors.Is(err, github.ErrUnhealthy) {
+			renderReport(ctx, reporters, entries)
+			return err
+		}
+		if errors.Is(err, github.ErrForkDisabled) {
+			logger.Warn("Skipped test fork of %s/%s: forking is disabled", repo.Owner, repo.Name)
+			entries[i].Status = report.StatusForkDisabled
+			continue
+		}
+		if err != nil {
+			logger.Error("Test fork of %s/%s failed: %v", repo.Owner, repo.Name, err)
+			entries[i].Status = report.StatusFailed
+			if opts.onError == onErrorStop {
+				renderReport(ctx, reporters, entries)
+				return fmt.Errorf("stopping after failed test fork of %s/%s: %w", repo.Owner, repo.Name, err)
+			}
+			continue
+		}
+		entries[i].Status = report.StatusTested
+		tested++
+		logger.Info("  - [%d/%d] Test fork of %s/%s verified as %s/%s", i+1, len(repos), repo.Owner, repo.Name, opts.sandbox, testName)
+	}
+
+	renderReport(ctx, reporters, entries)
+	logger.Info("Sandbox test completed. %d of %d forks verified; no original repositories were changed.", tested, len(repos))
+	return nil
+}
+
+// withRepoTimeout returns the context for processing one repository,
+// bounded by timeout if it is positive
+func withRepoTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// archiveNamespace returns the namespace a repository is archived into.
+// With -all-admin or -team each owner gets its own archive namespace, so
+// repositories are never forked into another owner's archive.
+func archiveNamespace(opts *options, repo github.Repository) string {
+	if opts.sandbox != "" {
+		return opts.sandbox
+	}
+	if opts.allAdmin || opts.team != "" {
+		return fmt.Sprintf("%s-archive", repo.Owner)
+	}
+	return fmt.Sprintf("%s-archive", opts.target)
+}
+
+// archiveNamespaces returns the sorted, distinct namespaces the repositories
+// will be archived into
+func archiveNamespaces(opts *options, repos []github.Repository) []string {
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, repo := range repos {
+		ns := archiveNamespace(opts, repo)
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// checkNamespaces verifies that every archive namespace exists, logging the
+// status of each, and returns an error listing any that are missing
+func checkNamespaces(ctx context.Context, repoArchiver *archiver.Archiver, namespaces []string) error {
+	logger.Info("Checking %d archive namespaces...", len(namespaces))
+	var missing []string
+	for _, ns := range namespaces {
+		if err := repoArchiver.VerifyNamespace(ctx, ns); err != nil {
+			if !errors.Is(err, github.ErrNamespaceNotFound) {
+				return err
+			}
+			logger.Warn("  - %s: missing", ns)
+			missing = append(missing, ns)
+			continue
+		}
+		logger.Info("  - %s: ok", ns)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %d must be created manually: %s",
+			github.ErrNamespaceNotFound, len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// listRepositories fetches the repositories of the target, or of the
+// requested teams, using search first when enabled. It reports whether the
+// repositories came from search, which returns only candidates.
+func listRepositories(ctx context.Context, client *github.Client, opts *options, teams [][2]string, inactivityPeriod time.Duration) ([]github.Repository, bool, error) {
+	var repos []github.Repository
+	var searched bool
+	var err error
+	if len(teams) > 0 {
+		for _, t := range teams {
+			logger.Info("Fetching repositories for team %s/%s...", t[0], t[1])
+			teamRepos, err := client.ListTeamRepositories(ctx, t[0], t[1])
+			if util.ForceProcessing(err) {
+				return nil, false, fmt.Errorf("failed to list team repositories: %w", err)
+			}
+			repos = append(repos, teamRepos...)
+		}
+	} else {
+		listed := false
+		if opts.search {
+			cutoff := opts.clock.Now().Add(-inactivityPeriod)
+			logger.Info("Searching for repositories of %s not pushed since %s...", opts.target, cutoff.Format("2006-01-02"))
+			found, capped, err := client.SearchInactiveRepositories(ctx, opts.target, opts.org, cutoff)
+			switch {
+			case err != nil:
+				logger.Warn("Search failed, falling back to full listing: %v", err)
+			case capped:
+				logger.Warn("Search results were capped, falling back to full listing; a longer -threshold or -min-inactivity narrows the search")
+			default:
+				repos = found
+				listed = true
+				searched = true
+			}
+		}
+		if !listed {
+			logger.Info("Fetching repositories for %s...", opts.target)
+			filters := github.ListFilters{Affiliation: opts.affiliation, Type: opts.repoType}
+			if opts.allAdmin {
+				filters.Affiliation = allAffiliations
+				filters.AdminOnly = true
+			}
+			repos, err = client.ListRepositories(ctx, opts.target, opts.org, filters)
+			if util.ForceProcessing(err) {
+				return nil, false, fmt.Errorf("failed to list repositories: %w", err)
+			}
+		}
+	}
+	// Teams share repositories, and affiliations overlap
+	return github.DedupeRepositories(repos), searched, nil
+}
+
+// quarantineRecord describes the outcome of quarantining a repository
+func quarantineRecord(repo github.Repository, err error) manifest.Record {
+	record := manifest.Record{
+		Owner:    repo.Owner,
+		Name:     repo.Name,
+		Strategy: "quarantine",
+		Status:   report.StatusQuarantined,
+	}
+	if err != nil {
+		record.Status = report.StatusFailed
+		record.Error = err.Error()
+	}
+	return record
+}
+
+// manifestRecord describes the outcome of archiving a repository
+func manifestRecord(repo github.Repository, result archiver.Result, err error) manifest.Record {
+	record := manifest.Record{
+		Owner:        repo.Owner,
+		Name:         repo.Name,
+		MovedFrom:    repo.MovedFrom,
+		Strategy:     string(result.Strategy),
+		Status:       report.StatusArchived,
+		Namespace:    result.Namespace,
+		ArchivedName: result.ArchivedName,
+		Deleted:      result.Deleted,
+		Visibility:   result.Visibility,
+		AtticPath:    result.AtticPath,
+		Readers:      result.Readers,
+	}
+	switch {
+	case errors.Is(err, github.ErrForkDisabled):
+		record.Status = report.StatusForkDisabled
+	case errors.Is(err, archiver.ErrMissingTopic):
+		record.Status = report.StatusMissingTopic
+	case errors.Is(err, archiver.ErrSignatureMismatch):
+		record.Status = report.StatusSignatureMismatch
+		record.Error = err.Error()
+	case err != nil:
+		record.Status = report.StatusFailed
+		record.Error = err.Error()
+	}
+	return record
+}
+
+// resumeFrom sorts repositories and, if a resume point is given, drops those
+// ordered before it so an interrupted run can continue where it stopped
+func resumeFrom(repos []github.Repository, from string) []github.Repository {
+	github.SortRepositories(repos)
+	if from == "" {
+		return repos
+	}
+
+	key := strings.ToLower(from)
+	skipped := 0
+	for skipped < len(repos) && repos[skipped].SortKey() < key {
+		skipped++
+	}
+	logger.Info("Resuming from %s: skipped %d repositories", from, skipped)
+	return repos[skipped:]
+}
+
+// checkArchiveLimits returns an error if the number of repositories about to
+// be archived exceeds the configured fraction of the owner's listing, or the
+// absolute count. Without a listing to compare with, total is 0 and only the
+// count is checked.
+func checkArchiveLimits(opts *options, candidates, total int) error {
+	if total > 0 {
+		fraction := float64(candidates) / float64(total)
+		logger.Info("%d of %d repositories (%.1f%%) selected for archiving", candidates, total, fraction*100)
+
+		if opts.maxFraction < 1 && fraction > opts.maxFraction {
+			return util.NewError(util.RefusedError, fmt.Errorf("%.1f%% of repositories would be archived, more than the -max-archive-fraction limit of %.1f%%; raise the limit if this is intended",
+				fraction*100, opts.maxFraction*100))
+		}
+	}
+	if opts.confirmCount > 0 && candidates > opts.confirmCount {
+		return util.NewError(util.RefusedError, fmt.Errorf("%d repositories would be archived, more than the -confirm-count limit of %d; raise the limit if this is intended",
+			candidates, opts.confirmCount))
+	}
+	return nil
+}
+
+// openEvents enables the event stream selected by -events-file or -events-fd
+// and returns a function that closes it
+func openEvents(opts *options) (func(), error) {
+	switch {
+	case opts.eventsFile == "-":
+		events.SetDefaultOutput(os.Stdout)
+	case opts.eventsFile != "":
+		f, err := os.OpenFile(opts.eventsFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open events file: %w", err)
+		}
+		events.SetDefaultOutput(f)
+		return func() { f.Close() }, nil
+	case opts.eventsFD > 0:
+		f := os.NewFile(uintptr(opts.eventsFD), "events")
+		if f == nil {
+			return nil, fmt.Errorf("invalid events file descriptor %d", opts.eventsFD)
+		}
+		events.SetDefaultOutput(f)
+		return func() { f.Close() }, nil
+	}
+	return func() {}, nil
+}
+
+// regexFilter creates the repository filter from the regex flags. Invalid
+// patterns are always an error, even with -force.
+func regexFilter(opts *options) (*filter.Filter, error) {
+	repoFilter := filter.New()
+	if opts.includeRegex != "" {
+		if err := repoFilter.AddIncludeRegex(opts.includeRegex); err != nil {
+			return nil, err
+		}
+	}
+	if opts.excludeRegex != "" {
+		if err := repoFilter.AddExcludeRegex(opts.excludeRegex); err != nil {
+			return nil, err
+		}
+	}
+	return repoFilter, nil
+}
+
+// loadExcludes adds the patterns of the -exclude-file sources and the ignore
+// file to the filter
+func loadExcludes(ctx context.Context, repoFilter *filter.Filter, opts *options) error {
+	for _, source := range strings.Split(opts.excludeFiles, ",") {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			continue
+		}
+		patterns, err := filter.LoadPatterns(ctx, source)
+		if err != nil {
+			return err
+		}
+		if err := repoFilter.AddExcludes(patterns...); err != nil {
+			return err
+		}
+	}
+
+	// The default ignore file is optional; an explicit one must exist
+	ignoreFile := opts.ignoreFile
+	if ignoreFile == "" {
+		if _, err := os.Stat(filter.IgnoreFile); err != nil {
+			return nil
+		}
+		ignoreFile = filter.IgnoreFile
+	}
+	patterns, err := filter.LoadPatterns(ctx, ignoreFile)
+	if err != nil {
+		return err
+	}
+	logger.Info("Loaded %d ignore rules from %s", len(patterns), ignoreFile)
+	return repoFilter.AddIgnoreRules(patterns...)
+}
+
+// firstYear is the earliest year accepted by -inactive-before-year, the year
+// GitHub launched
+const firstYear = 2008
+
+// inactivityRange returns the minimum and maximum inactivity used to select
+// repositories. -min-inactivity and -inactive-before-year take precedence
+// over -threshold, which defaults to the threshold of the strategy.
+func inactivityRange(opts *options) (time.Duration, time.Duration, error) {
+	years := opts.threshold
+	if years < 0 {
+		return 0, 0, fmt.Errorf("invalid -threshold %d: must be positive", years)
+	}
+	if years == 0 {
+		strategy, err := archiver.ParseStrategy(opts.strategy)
+		if err != nil {
+			return 0, 0, err
+		}
+		years = strategy.DefaultThreshold()
+		if opts.minInactivity == "" && opts.beforeYear == 0 {
+			logger.Info("Using the default threshold of the %s strategy, %dy", strategy, years)
+		}
+	}
+	minInactivity := time.Duration(years) * 365 * 24 * time.Hour
+	if opts.minInactivity != "" && opts.beforeYear != 0 {
+		return 0, 0, errors.New("-min-inactivity and -inactive-before-year cannot be combined")
+	}
+	if opts.minInactivity != "" {
+		d, err := util.ParseDuration(opts.minInactivity)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid -min-inactivity: %w", err)
+		}
+		minInactivity = d
+	}
+	if opts.beforeYear != 0 {
+		now := opts.clock.Now()
+		if opts.beforeYear < firstYear || opts.beforeYear > now.Year() {
+			return 0, 0, fmt.Errorf("invalid -inactive-before-year %d: must be between %d and %d", opts.beforeYear, firstYear, now.Year())
+		}
+		cutoff := time.Date(opts.beforeYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+		minInactivity = now.Sub(cutoff)
+	}
+
+	var maxInactivity time.Duration
+	if opts.maxInactivity != "" {
+		d, err := util.ParseDuration(opts.maxInactivity)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid -max-inactivity: %w", err)
+		}
+		if d < minInactivity {
+			return 0, 0, fmt.Errorf("-max-inactivity (%v) must not be less than the minimum inactivity (%v)", d, minInactivity)
+		}
+		maxInactivity = d
+	}
+	return minInactivity, maxInactivity, nil
+}
+
+// describeRange formats an inactivity range for log messages
+func describeRange(minInactivity, maxInactivity time.Duration) string {
+	days := func(d time.Duration) int { return int(d / (24 * time.Hour)) }
+	if maxInactivity == 0 {
+		return fmt.Sprintf("%d+ days", days(minInactivity))
+	}
+	return fmt.Sprintf("%d-%d days", days(minInactivity), days(maxInactivity))
+}
+
+// logRateUsage reports the API quota consumed during the run
+func logRateUsage(client *github.Client) {
+	usage := client.RateUsage()
+	if usage.Requests == 0 {
+		return
+	}
+	logger.Info("API usage: %d requests consumed, %d of %d remaining, resets at %s",
+		usage.Consumed(), usage.Remaining, usage.Limit, usage.Reset.Format("15:04:05"))
+	if usage.Retries > 0 {
+		logger.Info("API throttling: %d requests retried, %v spent in backoff", usage.Retries, usage.Backoff.Round(time.Second))
+	}
+}
+
+// runSummary is the end-of-run summary printed by -summary-format=json
+type runSummary struct {
+	Version string `json:"version"`
+	metrics.Snapshot
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// printSummary reports the run statistics, as log lines or as a single JSON
+// object on stdout
+func printSummary(opts *options, counters *metrics.Counters, startedAt time.Time, runErr error) {
+	stats := counters.Snapshot()
+	duration := time.Since(startedAt)
+
+	if opts.summaryFormat == summaryJSON {
+		v, _, _ := buildInfo()
+		summary := runSummary{Version: v, Snapshot: stats, DurationSeconds: duration.Seconds()}
+		if runErr != nil {
+			summary.Error = runErr.Error()
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(summary); err != nil {
+			logger.Error("Failed to write summary: %v", err)
+		}
+		return
+	}
+
+	logger.Info("Summary: %d repositories considered, %d inactive, %d archived, %d skipped, %d failed in %v",
+		stats.Total, stats.Inactive, stats.Archived, stats.Skipped, stats.Failed, duration.Round(time.Second))
+	if stats.TimedOut > 0 {
+		logger.Info("Summary: %d of the failed repositories timed out", stats.TimedOut)
+	}
+	if stats.Deferred > 0 {
+		logger.Info("Summary: %d repositories deferred at the -max-runtime deadline", stats.Deferred)
+	}
+	if stats.BytesBackedUp > 0 || stats.APICalls > 0 {
+		logger.Info("Summary: %d MB backed up, %d API calls", stats.BytesBackedUp/(1024*1024), stats.APICalls)
+	}
+	if stats.Reclaimed > 0 {
+		logger.Info("Summary: estimated storage reclaimed: %s", util.FormatBytes(stats.Reclaimed))
+	}
+	if stats.PrimaryHits > 0 || stats.SecondaryHits > 0 || stats.BackoffMillis > 0 {
+		logger.Info("Summary: rate limited %d times (primary) and %d times (secondary), %v in backoff",
+			stats.PrimaryHits, stats.SecondaryHits, time.Duration(stats.BackoffMillis)*time.Millisecond)
+		for _, phase := range stats.Throttling {
+			logger.Info("Summary:   %s: %d primary, %d secondary, %v in backoff",
+				phase.Phase, phase.PrimaryHits, phase.SecondaryHits, time.Duration(phase.BackoffMillis)*time.Millisecond)
+		}
+	}
+}
+
+// splitList splits a comma-separated list, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTeams splits a comma-separated list of org/team-slug pairs
+func parseTeams(value string) ([][2]string, error) {
+	var teams [][2]string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		org, slug, ok := strings.Cut(entry, "/")
+		if !ok || org == "" || slug == "" {
+			return nil, fmt.Errorf("invalid team %q: expected org/team-slug", entry)
+		}
+		teams = append(teams, [2]string{org, slug})
+	}
+	return teams, nil
+}
+
+// buildReporters creates the report sinks selected by the report flags.
+// -report-format and -report-template print to stdout, -report-file and
+// -report-webhook receive JSON.
+func buildReporters(opts *options, tmpl *template.Template) ([]report.Reporter, error) {
+	var reporters []report.Reporter
+	switch opts.reportFormat {
+	case "":
+	case "table":
+		reporters = append(reporters, report.NewWriterReporter(os.Stdout, report.TableFormat))
+	case "json":
+		reporters = append(reporters, report.NewWriterReporter(os.Stdout, report.RenderJSON))
+	default:
+		return nil, fmt.Errorf("invalid -report-format %q (valid: table, json)", opts.reportFormat)
+	}
+	if tmpl != nil {
+		reporters = append(reporters, report.NewWriterReporter(os.Stdout, report.TemplateFormat(tmpl)))
+	}
+	if opts.reportFile != "" {
+		reporters = append(reporters, report.NewFileReporter(opts.reportFile, report.RenderJSON))
+	}
+	if opts.reportWebhook != "" {
+		if !strings.HasPrefix(opts.reportWebhook, "http://") && !strings.HasPrefix(opts.reportWebhook, "https://") {
+			return nil, fmt.Errorf("invalid -report-webhook %q: must be an http(s) URL", opts.reportWebhook)
+		}
+		reporters = append(reporters, report.NewWebhookReporter(opts.reportWebhook))
+	}
+	return reporters, nil
+}
+
+// renderReport feeds the entries to every configured reporter
+func renderReport(ctx context.Context, reporters []report.Reporter, entries []report.Entry) {
+	for _, r := range reporters {
+		if err := r.Report(ctx, entries); err != nil {
+			logger.Error("Failed to render report: %v", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// readSinceFile returns the last successful run time stored in path, or the
+// zero time if the file does not exist yet
+func readSinceFile(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("No previous run recorded in %s, analyzing every repository", path)
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read since file: %w", err)
+	}
+
+	since, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp in since file %s: %w", path, err)
+	}
+	logger.Info("Last successful run was at %s", since.Format(time.RFC3339))
+	return since, nil
+}
+
+// writeSinceFile records t as the last successful run time, replacing path
+// atomically so an interrupted write never leaves a corrupt file
+func writeSinceFile(path string, t time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write since file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := fmt.Fprintln(tmp, t.UTC().Format(time.RFC3339)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write since file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write since file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write since file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+)
+
+// Build information, set at build time with
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=abc1234 -X main.date=2024-01-01"
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo fills in missing build information from the module and VCS data
+// recorded by the Go toolchain
+func buildInfo() (string, string, string) {
+	v, c, d := version, commit, date
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v, c, d
+	}
+	if v == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		v = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && c == "":
+			c = setting.Value
+		case setting.Key == "vcs.time" && d == "":
+			d = setting.Value
+		}
+	}
+	return v, c, d
+}
+
+// versionString describes the build for -version and the version subcommand
+func versionString() string {
+	v, c, d := buildInfo()
+	s := fmt.Sprintf("github-archiver %s", v)
+	if c != "" {
+		s += fmt.Sprintf(" (commit %s", c)
+		if d != "" {
+			s += fmt.Sprintf(", built %s", d)
+		}
+		s += ")"
+	}
+	return fmt.Sprintf("%s %s %s/%s", s, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// defaultUserAgent identifies the tool and its version in API requests
+func defaultUserAgent() string {
+	v, _, _ := buildInfo()
+	return github.DefaultUserAgent + "/" + v
+}
@@ -2,11 +2,14 @@ package analyzer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/eyedeekay/github-archiver/pkg/events"
 	"github.com/eyedeekay/github-archiver/pkg/github"
 	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/metrics"
 	"github.com/eyedeekay/github-archiver/pkg/util"
 )
 
@@ -14,6 +17,38 @@ import (
 type Analyzer struct {
 	client           *github.Client
 	inactivityPeriod time.Duration
+	maxInactivity    time.Duration
+	clock            util.Clock
+	checkOpenPulls   bool
+	skipTemplates    bool
+	skipMirrors      bool
+	checkWorkflows   bool
+	checkSecurity    bool
+	thresholdSource  github.ThresholdSource
+	continueOnError  bool
+	maxCommits       int
+	activeSince      time.Time
+	metrics          *metrics.Counters
+	minWatchers      int
+	recordSpared     bool
+	spared           []Spared
+	review           []Spared
+	failures         *util.MultiError
+	skipProtected    bool
+	weights          Weights
+	scoreThreshold   float64
+	generated        GeneratedRules
+	generatedPeriod  time.Duration
+	departedPeriod   time.Duration
+	deletePeriod     time.Duration
+	members          map[string]bool // org/login membership cache
+}
+
+// Spared is a repository that analysis did not select, with the signal
+// that kept it
+type Spared struct {
+	Repository github.Repository
+	Signal     string
 }
 
 // NewAnalyzer creates a new repository analyzer
@@ -21,19 +56,156 @@ func NewAnalyzer(client *github.Client, inactivityPeriod time.Duration) *Analyze
 	return &Analyzer{
 		client:           client,
 		inactivityPeriod: inactivityPeriod,
+		clock:            util.SystemClock{},
+		thresholdSource:  github.SourcePushed,
+	}
+}
+
+// SetClock replaces the clock used to compute the inactivity cutoff
+func (a *Analyzer) SetClock(clock util.Clock) {
+	a.clock = clock
+}
+
+// SetThresholdSource selects the repository timestamp used as the base for
+// last activity
+func (a *Analyzer) SetThresholdSource(source github.ThresholdSource) {
+	a.thresholdSource = source
+}
+
+// SetMaxInactivity limits selection to repositories inactive for less than
+// the given duration. Zero removes the upper bound.
+func (a *Analyzer) SetMaxInactivity(maxInactivity time.Duration) {
+	a.maxInactivity = maxInactivity
+}
+
+// SetCheckOpenPullRequests enables sparing repositories with an open pull
+// request updated within the inactivity period
+func (a *Analyzer) SetCheckOpenPullRequests(check bool) {
+	a.checkOpenPulls = check
+}
+
+// SetSkipTemplates controls whether template repositories are spared
+func (a *Analyzer) SetSkipTemplates(skip bool) {
+	a.skipTemplates = skip
+}
+
+// SetSkipMirrors controls whether mirror repositories are spared
+func (a *Analyzer) SetSkipMirrors(skip bool) {
+	a.skipMirrors = skip
+}
+
+// SetCheckWorkflows enables treating recent GitHub Actions workflow runs as
+// repository activity
+func (a *Analyzer) SetCheckWorkflows(check bool) {
+	a.checkWorkflows = check
+}
+
+// SetMaxCommits spares inactive repositories with more than the given number
+// of commits. Zero disables the check.
+func (a *Analyzer) SetMaxCommits(maxCommits int) {
+	a.maxCommits = maxCommits
+}
+
+// SetRecordSpared enables recording the repositories that are not selected,
+// for reporting through Spared
+func (a *Analyzer) SetRecordSpared(record bool) {
+	a.recordSpared = record
+}
+
+// Spared returns the repositories not selected by the last analysis, if
+// recording is enabled
+func (a *Analyzer) Spared() []Spared {
+	return a.spared
+}
+
+// Failures returns the errors of the repositories the last analysis could
+// not check and skipped, or nil if there were none
+func (a *Analyzer) Failures() error {
+	return a.failures.ErrorOrNil()
+}
+
+// spare records a repository that was not selected
+func (a *Analyzer) spare(repo github.Repository, signal string) {
+	if a.recordSpared {
+		a.spared = append(a.spared, Spared{Repository: repo, Signal: signal})
 	}
 }
 
+// SetGenerated selects repositories classified as generated by the rules
+// after the shorter period of inactivity. A period of 0 disables the check.
+func (a *Analyzer) SetGenerated(rules GeneratedRules, period time.Duration) {
+	a.generated = rules
+	a.generatedPeriod = period
+}
+
+// SetDepartedContributors selects organization repositories whose
+// contributors have all left the organization after the shorter period of
+// inactivity. A period of 0 disables the check.
+func (a *Analyzer) SetDepartedContributors(period time.Duration) {
+	a.departedPeriod = period
+}
+
+// SetDeleteThreshold marks inactive repositories past the longer period as
+// belonging to the deletion tier. A period of 0 disables tiering.
+func (a *Analyzer) SetDeleteThreshold(period time.Duration) {
+	a.deletePeriod = period
+}
+
+// SetScoring selects inactive repositories only if their archivability
+// score reaches the threshold. A threshold of 0 disables scoring.
+func (a *Analyzer) SetScoring(weights Weights, threshold float64) {
+	a.weights = weights
+	a.scoreThreshold = threshold
+}
+
+// SetSkipProtected spares inactive repositories whose default branch has
+// branch protection or a ruleset
+func (a *Analyzer) SetSkipProtected(skip bool) {
+	a.skipProtected = skip
+}
+
+// SetMinWatchers spares inactive repositories watched by at least the given
+// number of users. Zero disables the check.
+func (a *Analyzer) SetMinWatchers(minWatchers int) {
+	a.minWatchers = minWatchers
+}
+
+// SetActiveSince sets the time of the last run. Repositories whose listing
+// timestamp already shows activity after both that time and the inactivity
+// cutoff are counted as active without further API requests.
+func (a *Analyzer) SetActiveSince(since time.Time) {
+	a.activeSince = since
+}
+
+// SetMetrics records skipped, failed and inactive repositories in counters
+func (a *Analyzer) SetMetrics(counters *metrics.Counters) {
+	a.metrics = counters
+}
+
+// SetContinueOnError controls whether a repository that cannot be analyzed
+// is skipped rather than aborting the analysis
+func (a *Analyzer) SetContinueOnError(cont bool) {
+	a.continueOnError = cont
+}
+
 // FindInactiveRepositories identifies repositories with no activity
 // within the defined inactivity period
 func (a *Analyzer) FindInactiveRepositories(ctx context.Context, repos []github.Repository) ([]github.Repository, error) {
 	var inactiveRepos []github.Repository
 
-	now := time.Now()
+	now := a.clock.Now()
 	cutoffDate := now.Add(-a.inactivityPeriod)
 	logger.Debug("Inactivity threshold set to %v (before %s)", a.inactivityPeriod, cutoffDate.Format("2006-01-02"))
+	var oldestDate time.Time
+	if a.maxInactivity > 0 {
+		oldestDate = now.Add(-a.maxInactivity)
+		logger.Debug("Maximum inactivity set to %v (not before %s)", a.maxInactivity, oldestDate.Format("2006-01-02"))
+	}
 
 	logger.Info("Analyzing %d repositories for inactivity", len(repos))
+	a.spared = nil
+	a.review = nil
+	a.failures = &util.MultiError{}
 
 	for i, repo := range repos {
 		logger.Debug("[%d/%d] Checking repository %s/%s", i+1, len(repos), repo.Owner, repo.Name)
@@ -41,37 +213,384 @@ func (a *Analyzer) FindInactiveRepositories(ctx context.Context, repos []github.
 		// Skip already archived repositories
 		if repo.IsArchived {
 			logger.Debug("Skipping %s/%s - already archived", repo.Owner, repo.Name)
+			a.metrics.Inc(metrics.Skipped)
+			a.spare(repo, "already archived")
 			continue
 		}
 
-		// Get the latest activity timestamp
-		logger.Debug("Fetching last activity for %s/%s", repo.Owner, repo.Name)
-		lastActivity, err := a.client.GetLastActivity(ctx, repo.Owner, repo.Name)
-		if util.ForceProcessing(err) {
-			logger.Error("Failed to check activity for %s/%s: %v", repo.Owner, repo.Name, err)
-			return nil, fmt.Errorf("failed to check activity for %s/%s: %w", repo.Owner, repo.Name, err)
+		// Skip templates and mirrors, which are expected to sit unchanged
+		if a.skipTemplates && repo.IsTemplate {
+			logger.Info("Sparing %s/%s - template repository", repo.Owner, repo.Name)
+			a.metrics.Inc(metrics.Skipped)
+			a.spare(repo, "template")
+			continue
+		}
+		if a.skipMirrors && repo.IsMirror {
+			logger.Info("Sparing %s/%s - mirror repository", repo.Owner, repo.Name)
+			a.metrics.Inc(metrics.Skipped)
+			a.spare(repo, "mirror")
+			continue
+		}
+
+		// Skip repositories that have clearly been active since the last run,
+		// even under the shortest threshold
+		if base := repo.BaseActivity(a.thresholdSource); !a.activeSince.IsZero() &&
+			base.After(a.activeSince) && base.After(a.activeCutoff(now)) {
+			logger.Debug("Repository %s/%s active since the last run (%s), skipping analysis",
+				repo.Owner, repo.Name, base.Format("2006-01-02"))
+			a.metrics.Inc(metrics.Skipped)
+			repo.LastActivity = base
+			a.spare(repo, "active since last run")
+			continue
+		}
+
+		// Get the latest activity timestamp, stopping once the repository
+		// is active under even the shortest threshold
+		activity, err := a.client.GetLastActivity(ctx, repo, github.ActivityQuery{
+			Source:    a.thresholdSource,
+			Cutoff:    a.activeCutoff(now),
+			Workflows: a.checkWorkflows,
+		})
+		if err != nil {
+			if err := a.repoFailed(repo, "activity", err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		lastActivity, signal := activity.Latest()
+
+		// A bad timestamp would make the repository look ancient, so hold it
+		// for review rather than archive it
+		if reason := implausible(lastActivity, now); reason != "" {
+			logger.Warn("Holding %s/%s for manual review - %s", repo.Owner, repo.Name, reason)
+			a.metrics.Inc(metrics.Skipped)
+			repo.LastActivity = lastActivity
+			a.holdForReview(repo, reason)
+			continue
+		}
+
+		// Generated artifacts are selected after a shorter period
+		repoCutoff := cutoffDate
+		if a.generatedPeriod > 0 && lastActivity.Before(now.Add(-a.generatedPeriod)) && !lastActivity.Before(cutoffDate) {
+			generated, reason, err := a.classifyGenerated(ctx, repo)
+			if err != nil {
+				if err := a.repoFailed(repo, "languages", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if generated {
+				logger.Info("Repository %s/%s looks generated (%s), using the %v threshold",
+					repo.Owner, repo.Name, reason, a.generatedPeriod)
+				repoCutoff = now.Add(-a.generatedPeriod)
+				signal += ", generated"
+			} else {
+				logger.Debug("Repository %s/%s does not look generated", repo.Owner, repo.Name)
+			}
+		}
+
+		// So are repositories nobody left in the organization works on
+		if a.departedPeriod > 0 && lastActivity.Before(now.Add(-a.departedPeriod)) && !lastActivity.Before(repoCutoff) {
+			departed, reason, err := a.contributorsDeparted(ctx, repo)
+			if err != nil {
+				if err := a.repoFailed(repo, "contributors", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if departed {
+				logger.Info("Repository %s/%s has no contributors left in %s (%s), using the %v threshold",
+					repo.Owner, repo.Name, repo.Owner, reason, a.departedPeriod)
+				repoCutoff = now.Add(-a.departedPeriod)
+				signal += ", contributors departed"
+			} else {
+				logger.Debug("Repository %s/%s still has contributors in %s (%s)", repo.Owner, repo.Name, repo.Owner, reason)
+			}
 		}
 
 		// Add repository details to the result
 		repo.LastActivity = lastActivity
+		repo.ActivitySignal = signal
 
 		// Format the duration since last activity for logging
 		inactiveDuration := now.Sub(lastActivity).Round(24 * time.Hour)
 
+		// Spare repositories with recently updated open pull requests
+		if a.checkOpenPulls && lastActivity.Before(repoCutoff) {
+			pullActivity, err := a.client.GetLatestOpenPullRequest(ctx, repo.Owner, repo.Name)
+			if err != nil {
+				if err := a.repoFailed(repo, "pull requests", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if pullActivity.After(repoCutoff) {
+				logger.Debug("Repository %s/%s has an open pull request updated %s, sparing it",
+					repo.Owner, repo.Name, pullActivity.Format("2006-01-02"))
+				a.metrics.Inc(metrics.Skipped)
+				a.spare(repo, "open pull request")
+				continue
+			}
+		}
+
+		// Spare repositories automation still keeps secure
+		if a.checkSecurity && lastActivity.Before(repoCutoff) {
+			security, err := a.securityActivity(ctx, repo, repoCutoff)
+			if err != nil {
+				if err := a.repoFailed(repo, "security", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if security.Ref != "" {
+				logger.Info("Sparing %s/%s - security maintenance: %s updated %s",
+					repo.Owner, repo.Name, security.Ref, security.Updated.Format("2006-01-02"))
+				a.metrics.Inc(metrics.Skipped)
+				a.spare(repo, "security: "+security.Ref)
+				continue
+			}
+		}
+
+		// Leave repositories older than the selected range for manual review
+		if lastActivity.Before(oldestDate) {
+			logger.Debug("Repository %s/%s is outside the inactivity range (last activity: %s, %v ago)",
+				repo.Owner, repo.Name, lastActivity.Format("2006-01-02"), inactiveDuration)
+			a.metrics.Inc(metrics.Skipped)
+			a.spare(repo, "beyond max inactivity")
+			continue
+		}
+
+		// Spare inactive repositories that represent significant work
+		if a.maxCommits > 0 && lastActivity.Before(repoCutoff) {
+			commits, err := a.client.CountCommits(ctx, repo.Owner, repo.Name)
+			if err != nil {
+				if err := a.repoFailed(repo, "commits", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if commits > a.maxCommits {
+				logger.Info("Sparing %s/%s - %d commits, more than the limit of %d", repo.Owner, repo.Name, commits, a.maxCommits)
+				a.metrics.Inc(metrics.Skipped)
+				a.spare(repo, fmt.Sprintf("%d commits", commits))
+				continue
+			}
+		}
+
+		// Spare inactive repositories that people still follow
+		if a.minWatchers > 0 && lastActivity.Before(repoCutoff) {
+			watchers := repo.Watchers
+			if watchers == 0 {
+				watchers, err = a.client.GetWatchers(ctx, repo.Owner, repo.Name)
+				if err != nil {
+					if err := a.repoFailed(repo, "watchers", err); err != nil {
+						return nil, err
+					}
+					continue
+				}
+			}
+			if watchers >= a.minWatchers {
+				logger.Info("Sparing %s/%s - %d watchers (%d stars), at least the limit of %d",
+					repo.Owner, repo.Name, watchers, repo.Stars, a.minWatchers)
+				a.metrics.Inc(metrics.Skipped)
+				a.spare(repo, fmt.Sprintf("%d watchers", watchers))
+				continue
+			}
+			logger.Debug("Repository %s/%s has %d watchers and %d stars", repo.Owner, repo.Name, watchers, repo.Stars)
+		}
+
+		// Spare governed repositories, which tend to be important ones
+		if a.skipProtected && repo.DefaultBranch != "" && lastActivity.Before(repoCutoff) {
+			protected, kind, err := a.client.GetBranchProtection(ctx, repo.Owner, repo.Name, repo.DefaultBranch)
+			if err != nil {
+				if err := a.repoFailed(repo, "branch protection", err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if protected {
+				logger.Info("Sparing %s/%s - %s on %s", repo.Owner, repo.Name, kind, repo.DefaultBranch)
+				a.metrics.Inc(metrics.Skipped)
+				a.spare(repo, kind)
+				continue
+			}
+			logger.Debug("Default branch %s of %s/%s is not protected", repo.DefaultBranch, repo.Owner, repo.Name)
+		}
+
+		// Weigh recency against popularity, open issues and size
+		if a.scoreThreshold > 0 && lastActivity.Before(repoCutoff) {
+			repo.Score = Score(repo, now, a.weights)
+			if repo.Score < a.scoreThreshold {
+				logger.Info("Sparing %s/%s - score %.0f below %.0f (%d stars, %d open issues, %d KB)",
+					repo.Owner, repo.Name, repo.Score, a.scoreThreshold, repo.Stars, repo.OpenIssues, repo.Size)
+				a.metrics.Inc(metrics.Skipped)
+				a.spare(repo, fmt.Sprintf("score %.0f", repo.Score))
+				continue
+			}
+			logger.Debug("Repository %s/%s scores %.0f", repo.Owner, repo.Name, repo.Score)
+		}
+
+		events.Emit(events.Event{
+			Type:  events.RepoAnalyzed,
+			Owner: repo.Owner,
+			Repo:  repo.Name,
+			Data: map[string]interface{}{
+				"last_activity": lastActivity,
+				"inactive":      lastActivity.Before(repoCutoff),
+			},
+		})
+
 		// Check if the repository is inactive
-		if lastActivity.Before(cutoffDate) {
+		if lastActivity.Before(repoCutoff) {
 			logger.Debug("Repository %s/%s is inactive (last activity: %s, %v ago)",
 				repo.Owner, repo.Name, lastActivity.Format("2006-01-02"), inactiveDuration)
+			if a.deletePeriod > 0 && lastActivity.Before(now.Add(-a.deletePeriod)) {
+				logger.Debug("Repository %s/%s is past the deletion threshold of %v", repo.Owner, repo.Name, a.deletePeriod)
+				repo.DeleteTier = true
+			}
 			inactiveRepos = append(inactiveRepos, repo)
+			a.metrics.Inc(metrics.Inactive)
 		} else {
 			logger.Debug("Repository %s/%s is active (last activity: %s, %v ago)",
 				repo.Owner, repo.Name, lastActivity.Format("2006-01-02"), inactiveDuration)
+			a.spare(repo, signal)
 		}
-
-		// Add a small delay to prevent rate limiting
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	logger.Info("Found %d inactive repositories out of %d total", len(inactiveRepos), len(repos))
 	return inactiveRepos, nil
 }
+
+// classifyGenerated reports whether a repository looks like generated
+// artifacts, and why. Names are checked first since they cost no request.
+func (a *Analyzer) classifyGenerated(ctx context.Context, repo github.Repository) (bool, string, error) {
+	if ok, reason := a.generated.MatchName(repo.Name); ok {
+		return true, reason, nil
+	}
+	if len(a.generated.Languages) == 0 {
+		return false, "", nil
+	}
+	languages, err := a.client.ListLanguages(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return false, "", err
+	}
+	ok, reason := a.generated.MatchLanguages(languages)
+	return ok, reason, nil
+}
+
+// contributorsDeparted reports whether none of a repository's top
+// contributors is still a member of the owning organization, and why.
+// Membership lookups are cached across repositories.
+func (a *Analyzer) contributorsDeparted(ctx context.Context, repo github.Repository) (bool, string, error) {
+	logins, err := a.client.ListTopContributors(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return false, "", err
+	}
+	if len(logins) == 0 {
+		return false, "no contributors listed", nil
+	}
+
+	if a.members == nil {
+		a.members = make(map[string]bool)
+	}
+	for _, login := range logins {
+		key := repo.Owner + "/" + login
+		member, ok := a.members[key]
+		if !ok {
+			member, err = a.client.IsOrgMember(ctx, repo.Owner, login)
+			if err != nil {
+				return false, "", err
+			}
+			a.members[key] = member
+		}
+		if member {
+			return false, login + " is a member", nil
+		}
+	}
+	return true, fmt.Sprintf("none of %d contributors is a member", len(logins)), nil
+}
+
+// FindEmptyRepositories identifies repositories without any commits,
+// regardless of activity. The listed size is not trusted on its own, since
+// it is updated lazily, so every candidate costs one commits API request.
+// Templates and mirrors are spared as by FindInactiveRepositories.
+func (a *Analyzer) FindEmptyRepositories(ctx context.Context, repos []github.Repository) ([]github.Repository, error) {
+	var emptyRepos []github.Repository
+
+	logger.Info("Checking %d repositories for commits", len(repos))
+	a.failures = &util.MultiError{}
+
+	for i, repo := range repos {
+		logger.Debug("[%d/%d] Checking repository %s/%s", i+1, len(repos), repo.Owner, repo.Name)
+
+		if repo.IsArchived {
+			logger.Debug("Skipping %s/%s - already archived", repo.Owner, repo.Name)
+			a.metrics.Inc(metrics.Skipped)
+			continue
+		}
+
+		// Skip templates and mirrors before spending a request on them
+		if a.skipTemplates && repo.IsTemplate {
+			logger.Info("Sparing %s/%s - template repository", repo.Owner, repo.Name)
+			a.metrics.Inc(metrics.Skipped)
+			a.spare(repo, "template")
+			continue
+		}
+		if a.skipMirrors && repo.IsMirror {
+			logger.Info("Sparing %s/%s - mirror repository", repo.Owner, repo.Name)
+			a.metrics.Inc(metrics.Skipped)
+			a.spare(repo, "mirror")
+			continue
+		}
+
+		commits, err := a.client.CountCommits(ctx, repo.Owner, repo.Name)
+		if err != nil {
+			if err := a.repoFailed(repo, "commits", err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if commits > 0 {
+			logger.Debug("Repository %s/%s has %d commits", repo.Owner, repo.Name, commits)
+			continue
+		}
+
+		logger.Debug("Repository %s/%s is empty: the commits API reports no commits (listed size %d KB)",
+			repo.Owner, repo.Name, repo.Size)
+		repo.LastActivity = repo.BaseActivity(a.thresholdSource)
+		emptyRepos = append(emptyRepos, repo)
+		a.metrics.Inc(metrics.Inactive)
+	}
+
+	logger.Info("Found %d empty repositories out of %d total", len(emptyRepos), len(repos))
+	return emptyRepos, nil
+}
+
+// activeCutoff returns the cutoff of the shortest configured threshold. A
+// repository active after it is spared whatever else analysis finds, so its
+// remaining activity signals need not be fetched.
+func (a *Analyzer) activeCutoff(now time.Time) time.Time {
+	period := a.inactivityPeriod
+	for _, p := range []time.Duration{a.generatedPeriod, a.departedPeriod} {
+		if p > 0 && p < period {
+			period = p
+		}
+	}
+	return now.Add(-period)
+}
+
+// repoFailed handles a failed check of a repository. It returns nil if the
+// repository should be skipped and analysis continued, collecting the error
+// for Failures, or the error that should abort the analysis. An unhealthy
+// API always aborts.
+func (a *Analyzer) repoFailed(repo github.Repository, check string, err error) error {
+	logger.Error("Failed to check %s for %s/%s: %v", check, repo.Owner, repo.Name, err)
+	events.Emit(events.Event{Type: events.Error, Owner: repo.Owner, Repo: repo.Name, Message: err.Error()})
+	a.metrics.Fail(repo.Owner, repo.Name, err)
+	failure := fmt.Errorf("failed to check %s for %s/%s: %w", check, repo.Owner, repo.Name, err)
+	if a.continueOnError && !errors.Is(err, github.ErrUnhealthy) {
+		a.failures.Add(failure)
+		return nil
+	}
+	return failure
+}
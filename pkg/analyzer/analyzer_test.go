@@ -0,0 +1,194 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/metrics"
+)
+
+// fixedClock is a Clock stopped at one instant
+type fixedClock time.Time
+
+// Now returns the instant the clock is stopped at
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// newFixtureAnalyzer creates an analyzer stopped at now whose client answers
+// from an empty fixture directory, so repositories have no issues, releases
+// or workflow runs and their push time is their last activity
+func newFixtureAnalyzer(t *testing.T, now time.Time, period time.Duration) *Analyzer {
+	t.Helper()
+	client, err := github.NewFixtureClient(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAnalyzer(client, period)
+	a.SetClock(fixedClock(now))
+	a.SetRecordSpared(true)
+	return a
+}
+
+// names returns the names of repositories in order
+func names(repos []github.Repository) []string {
+	var result []string
+	for _, repo := range repos {
+		result = append(result, repo.Name)
+	}
+	return result
+}
+
+func TestFindInactiveRepositoriesBoundary(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	period := 365 * 24 * time.Hour
+	cutoff := now.Add(-period)
+
+	tests := []struct {
+		name     string
+		pushed   time.Time
+		inactive bool
+	}{
+		{"a second before the cutoff", cutoff.Add(-time.Second), true},
+		{"on the cutoff", cutoff, false},
+		{"a second after the cutoff", cutoff.Add(time.Second), false},
+		{"now", now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newFixtureAnalyzer(t, now, period)
+			repo := github.Repository{Owner: "acme", Name: "tool", PushedAt: tt.pushed}
+			inactive, err := a.FindInactiveRepositories(context.Background(), []github.Repository{repo})
+			if err != nil {
+				t.Fatalf("FindInactiveRepositories: %v", err)
+			}
+			if got := len(inactive) == 1; got != tt.inactive {
+				t.Errorf("inactive = %v, want %v", got, tt.inactive)
+			}
+			if !tt.inactive && len(a.Spared()) != 1 {
+				t.Errorf("spared %d repositories, want 1", len(a.Spared()))
+			}
+		})
+	}
+}
+
+func TestFindInactiveRepositoriesRange(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	period := 365 * 24 * time.Hour
+	maxInactivity := 2 * period
+	oldest := now.Add(-maxInactivity)
+
+	a := newFixtureAnalyzer(t, now, period)
+	a.SetMaxInactivity(maxInactivity)
+	a.SetDeleteThreshold(maxInactivity - 24*time.Hour)
+	repos := []github.Repository{
+		{Owner: "acme", Name: "beyond", PushedAt: oldest.Add(-time.Second)},
+		{Owner: "acme", Name: "oldest", PushedAt: oldest},
+		{Owner: "acme", Name: "recent", PushedAt: now.Add(-period).Add(-time.Second)},
+	}
+	inactive, err := a.FindInactiveRepositories(context.Background(), repos)
+	if err != nil {
+		t.Fatalf("FindInactiveRepositories: %v", err)
+	}
+	if got := names(inactive); len(got) != 2 || got[0] != "oldest" || got[1] != "recent" {
+		t.Fatalf("inactive = %q, want [oldest recent]", got)
+	}
+	if !inactive[0].DeleteTier || inactive[1].DeleteTier {
+		t.Errorf("delete tier = %v, %v, want true, false", inactive[0].DeleteTier, inactive[1].DeleteTier)
+	}
+	if spared := a.Spared(); len(spared) != 1 || spared[0].Signal != "beyond max inactivity" {
+		t.Errorf("spared = %+v, want beyond only", spared)
+	}
+}
+
+func TestFindInactiveRepositoriesFutureTimestamp(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	a := newFixtureAnalyzer(t, now, 365*24*time.Hour)
+	repos := []github.Repository{
+		{Owner: "acme", Name: "skewed", PushedAt: now.Add(clockSkew)},
+		{Owner: "acme", Name: "future", PushedAt: now.Add(clockSkew + time.Second)},
+	}
+	inactive, err := a.FindInactiveRepositories(context.Background(), repos)
+	if err != nil {
+		t.Fatalf("FindInactiveRepositories: %v", err)
+	}
+	if len(inactive) != 0 {
+		t.Errorf("inactive = %q, want none", names(inactive))
+	}
+	if review := a.Review(); len(review) != 1 || review[0].Repository.Name != "future" {
+		t.Errorf("held for review = %+v, want future only", review)
+	}
+}
+
+func TestFindInactiveRepositoriesActiveSince(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	period := 365 * 24 * time.Hour
+	lastRun := now.Add(-24 * time.Hour)
+
+	a := newFixtureAnalyzer(t, now, period)
+	counters := metrics.New()
+	a.SetMetrics(counters)
+	a.SetActiveSince(lastRun)
+	repos := []github.Repository{
+		{Owner: "acme", Name: "active", PushedAt: lastRun.Add(time.Hour)},
+		{Owner: "acme", Name: "inactive", PushedAt: now.Add(-2 * period)},
+	}
+	inactive, err := a.FindInactiveRepositories(context.Background(), repos)
+	if err != nil {
+		t.Fatalf("FindInactiveRepositories: %v", err)
+	}
+	if got := names(inactive); len(got) != 1 || got[0] != "inactive" {
+		t.Fatalf("inactive = %q, want [inactive]", got)
+	}
+	stats := counters.Snapshot()
+	if stats.Skipped != 1 || stats.Inactive != 1 {
+		t.Errorf("skipped %d and inactive %d, want 1 each", stats.Skipped, stats.Inactive)
+	}
+	if spared := a.Spared(); len(spared) != 1 || spared[0].Signal != "active since last run" {
+		t.Errorf("spared = %+v, want active since last run", spared)
+	}
+}
+
+func TestFindEmptyRepositoriesSparesTemplatesAndMirrors(t *testing.T) {
+	// Only the plain repository has a commits fixture, an empty list
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "repos", "acme", "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "repos", "acme", "empty", "commits.json"), []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client, err := github.NewFixtureClient(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAnalyzer(client, 365*24*time.Hour)
+	a.SetRecordSpared(true)
+	counters := metrics.New()
+	a.SetMetrics(counters)
+	a.SetSkipTemplates(true)
+	a.SetSkipMirrors(true)
+	repos := []github.Repository{
+		{Owner: "acme", Name: "template", IsTemplate: true},
+		{Owner: "acme", Name: "mirror", IsMirror: true},
+		{Owner: "acme", Name: "empty"},
+	}
+	empty, err := a.FindEmptyRepositories(context.Background(), repos)
+	if err != nil {
+		t.Fatalf("FindEmptyRepositories: %v", err)
+	}
+	if got := names(empty); len(got) != 1 || got[0] != "empty" {
+		t.Fatalf("empty = %q, want [empty]", got)
+	}
+	if stats := counters.Snapshot(); stats.Skipped != 2 {
+		t.Errorf("skipped %d, want 2", stats.Skipped)
+	}
+	spared := a.Spared()
+	if len(spared) != 2 || spared[0].Signal != "template" || spared[1].Signal != "mirror" {
+		t.Errorf("spared = %+v, want the template and the mirror", spared)
+	}
+}
@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// FilterDescription keeps the repositories without a description, or those
+// with one when described is true, and returns the others as spared. A
+// description of only whitespace counts as none.
+func FilterDescription(repos []github.Repository, described bool) (candidates []github.Repository, spared []Spared) {
+	for _, repo := range repos {
+		has := strings.TrimSpace(repo.Description) != ""
+		switch {
+		case has == described:
+			candidates = append(candidates, repo)
+		case has:
+			logger.Debug("Skipping %s/%s - has a description", repo.Owner, repo.Name)
+			spared = append(spared, Spared{Repository: repo, Signal: "has description"})
+		default:
+			logger.Debug("Skipping %s/%s - no description", repo.Owner, repo.Name)
+			spared = append(spared, Spared{Repository: repo, Signal: "no description"})
+		}
+	}
+	if described {
+		logger.Info("%d of %d repositories have a description", len(candidates), len(repos))
+	} else {
+		logger.Info("%d of %d repositories have no description", len(candidates), len(repos))
+	}
+	return candidates, spared
+}
@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// GeneratedRules classify repositories that hold generated artifacts, such
+// as published sites or vendored mirrors, which are safe to archive sooner
+type GeneratedRules struct {
+	// Names are globs matched against the lowercased repository name
+	Names []string
+	// Languages mark a repository as generated when every language GitHub
+	// detects in it is one of these
+	Languages []string
+}
+
+// DefaultGeneratedRules match GitHub Pages sites, build output and mirrors
+var DefaultGeneratedRules = GeneratedRules{
+	Names:     []string{"*.github.io", "*-gh-pages", "*-pages", "*-site", "*-dist", "*-build", "*-mirror", "mirror-*", "vendor-*"},
+	Languages: []string{"HTML", "CSS", "SCSS", "JavaScript"},
+}
+
+// Validate reports whether every name glob is well formed
+func (r GeneratedRules) Validate() error {
+	for _, pattern := range r.Names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid generated name pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// MatchName reports whether the name matches a generated name pattern, and
+// which one
+func (r GeneratedRules) MatchName(name string) (bool, string) {
+	name = strings.ToLower(name)
+	for _, pattern := range r.Names {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true, fmt.Sprintf("name matches %q", pattern)
+		}
+	}
+	return false, ""
+}
+
+// MatchLanguages reports whether every detected language is a generated
+// one. Repositories without detected languages do not match.
+func (r GeneratedRules) MatchLanguages(languages map[string]int) (bool, string) {
+	if len(languages) == 0 || len(r.Languages) == 0 {
+		return false, ""
+	}
+	allowed := make(map[string]bool, len(r.Languages))
+	for _, lang := range r.Languages {
+		allowed[strings.ToLower(lang)] = true
+	}
+	var found []string
+	for lang := range languages {
+		if !allowed[strings.ToLower(lang)] {
+			return false, ""
+		}
+		found = append(found, lang)
+	}
+	sort.Strings(found)
+	return true, "only " + strings.Join(found, ", ")
+}
@@ -0,0 +1,40 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+)
+
+// GitHubLaunch is when GitHub opened; no repository activity predates it
+var GitHubLaunch = time.Date(2008, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+// clockSkew is how far in the future a timestamp may be before it is
+// implausible, allowing for a local clock running behind GitHub's
+const clockSkew = 24 * time.Hour
+
+// implausible describes why a last activity timestamp cannot be right, such
+// as a zero value from a missing field, or returns "" if it is plausible
+func implausible(t, now time.Time) string {
+	switch {
+	case t.IsZero():
+		return "no activity timestamp"
+	case t.Before(GitHubLaunch):
+		return "activity before GitHub existed (" + t.Format("2006-01-02") + ")"
+	case t.After(now.Add(clockSkew)):
+		return "activity in the future (" + t.Format("2006-01-02") + ")"
+	}
+	return ""
+}
+
+// Review returns the repositories the last analysis held back for manual
+// review because their activity timestamps are implausible. They are
+// recorded whether or not spared repositories are.
+func (a *Analyzer) Review() []Spared {
+	return a.review
+}
+
+// holdForReview records a repository with an implausible timestamp
+func (a *Analyzer) holdForReview(repo github.Repository, reason string) {
+	a.review = append(a.review, Spared{Repository: repo, Signal: reason})
+}
@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"context"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// Probe is the breakdown of a repository's activity by signal
+type Probe struct {
+	Repository github.Repository
+	Activity   github.Activity
+}
+
+// ProbeRepositories fetches every activity signal of each repository without
+// selecting any, so users can see which signals decide activity in their
+// account. Archived repositories are left out. It is read-only.
+func (a *Analyzer) ProbeRepositories(ctx context.Context, repos []github.Repository) ([]Probe, error) {
+	logger.Info("Probing activity signals of %d repositories", len(repos))
+	a.failures = &util.MultiError{}
+
+	var probes []Probe
+	for i, repo := range repos {
+		if repo.IsArchived {
+			logger.Debug("Skipping %s/%s - already archived", repo.Owner, repo.Name)
+			continue
+		}
+		logger.Debug("[%d/%d] Probing repository %s/%s", i+1, len(repos), repo.Owner, repo.Name)
+		activity, err := a.client.ProbeActivity(ctx, repo)
+		if err != nil {
+			if err := a.repoFailed(repo, "activity signals", err); err != nil {
+				return probes, err
+			}
+			continue
+		}
+		probes = append(probes, Probe{Repository: repo, Activity: activity})
+	}
+	return probes, nil
+}
@@ -0,0 +1,63 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// DefaultGroupPattern groups generations of a repository such as
+// service-v1, service-v2 and service-3 under the name before the version
+const DefaultGroupPattern = `^(.+?)[-_.]?v?\d+$`
+
+// KeepLatest applies a generational retention policy: repositories are
+// grouped by the first capture group of pattern matched against their name,
+// or the whole match if it has none, and the n most recently active members
+// of each group are kept. Activity is the listing's timestamp for source.
+// Repositories whose names do not match are left in candidates, which are
+// returned sorted.
+func KeepLatest(repos []github.Repository, pattern *regexp.Regexp, n int, source github.ThresholdSource) (candidates []github.Repository, kept []Spared) {
+	groups := make(map[string][]github.Repository)
+	var keys []string
+	for _, repo := range repos {
+		m := pattern.FindStringSubmatch(repo.Name)
+		if m == nil {
+			candidates = append(candidates, repo)
+			continue
+		}
+		key := m[0]
+		if len(m) > 1 {
+			key = m[1]
+		}
+		key = repo.Owner + "/" + strings.ToLower(key)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], repo)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		members := groups[key]
+		sort.SliceStable(members, func(i, j int) bool {
+			return members[i].BaseActivity(source).After(members[j].BaseActivity(source))
+		})
+
+		var names []string
+		for i, repo := range members {
+			if i < n {
+				names = append(names, repo.Name)
+				kept = append(kept, Spared{Repository: repo, Signal: fmt.Sprintf("latest %d in group %s", n, key)})
+				continue
+			}
+			candidates = append(candidates, repo)
+		}
+		logger.Info("Group %s: %d members, keeping %s", key, len(members), strings.Join(names, ", "))
+	}
+	github.SortRepositories(candidates)
+	return candidates, kept
+}
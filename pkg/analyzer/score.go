@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+)
+
+// Weights balance the factors of the archivability score. Only their
+// relative sizes matter.
+type Weights struct {
+	Recency float64 // time since the last activity
+	Stars   float64 // few stars
+	Issues  float64 // few open issues and pull requests
+	Size    float64 // small repositories
+}
+
+// DefaultWeights favor recency, with popularity and size as tie-breakers
+var DefaultWeights = Weights{Recency: 0.6, Stars: 0.2, Issues: 0.1, Size: 0.1}
+
+// Scales at which each factor contributes half of its weight, or for
+// recency, its full weight
+const (
+	recencyScale = 5 * 365 * 24 * time.Hour
+	starsScale   = 10
+	issuesScale  = 5
+	sizeScaleKB  = 100 * 1024
+)
+
+// Validate reports whether the weights can produce a score
+func (w Weights) Validate() error {
+	if w.Recency < 0 || w.Stars < 0 || w.Issues < 0 || w.Size < 0 {
+		return errors.New("score weights must not be negative")
+	}
+	if w.total() == 0 {
+		return errors.New("at least one score weight must be positive")
+	}
+	return nil
+}
+
+// total returns the sum of the weights
+func (w Weights) total() float64 {
+	return w.Recency + w.Stars + w.Issues + w.Size
+}
+
+// Score rates how safe a repository is to archive from 0 (keep) to 100
+// (archive). Each factor maps to 0..1: recency grows linearly to 1 at five
+// years of inactivity, while stars, open issues and size decay from 1 and
+// halve at 10 stars, 5 open issues and 100 MB respectively.
+func Score(repo github.Repository, now time.Time, w Weights) float64 {
+	recency := float64(now.Sub(repo.LastActivity)) / float64(recencyScale)
+	switch {
+	case recency < 0:
+		recency = 0
+	case recency > 1:
+		recency = 1
+	}
+	stars := decay(float64(repo.Stars), starsScale)
+	issues := decay(float64(repo.OpenIssues), issuesScale)
+	size := decay(float64(repo.Size), sizeScaleKB)
+
+	weighted := w.Recency*recency + w.Stars*stars + w.Issues*issues + w.Size*size
+	return 100 * weighted / w.total()
+}
+
+// decay maps a non-negative count to 1 at zero, halving at scale
+func decay(value, scale float64) float64 {
+	return 1 / (1 + value/scale)
+}
@@ -0,0 +1,31 @@
+package analyzer
+
+import (
+	"context"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+)
+
+// SetCheckSecurity enables sparing inactive repositories that automation
+// still keeps secure: those with a Dependabot pull request or an open
+// security advisory updated within the inactivity period. It costs up to
+// two API requests per candidate.
+func (a *Analyzer) SetCheckSecurity(check bool) {
+	a.checkSecurity = check
+}
+
+// securityActivity returns the first piece of security maintenance on a
+// repository updated after the cutoff, Dependabot pull requests first, or
+// the zero SecurityActivity if there is none
+func (a *Analyzer) securityActivity(ctx context.Context, repo github.Repository, cutoff time.Time) (github.SecurityActivity, error) {
+	pull, err := a.client.GetLatestDependabotPullRequest(ctx, repo.Owner, repo.Name)
+	if err != nil || pull.Updated.After(cutoff) {
+		return pull, err
+	}
+	advisory, err := a.client.GetLatestSecurityAdvisory(ctx, repo.Owner, repo.Name)
+	if err != nil || advisory.Updated.After(cutoff) {
+		return advisory, err
+	}
+	return github.SecurityActivity{}, nil
+}
@@ -2,44 +2,297 @@ package archiver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/eyedeekay/github-archiver/pkg/github"
 	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/metrics"
 	"github.com/eyedeekay/github-archiver/pkg/util"
 )
 
+// Strategy selects how an inactive repository is archived
+type Strategy string
+
+const (
+	// StrategyFork forks the repository into the archive namespace and marks
+	// the fork archived. The original is only deleted when deletion is allowed.
+	StrategyFork Strategy = "fork"
+	// StrategyArchive marks the repository archived in place
+	StrategyArchive Strategy = "archive"
+	// StrategyDelete deletes the repository without keeping a copy
+	StrategyDelete Strategy = "delete"
+	// StrategyAttic commits the repository's tree under owner/repo/ in a
+	// single attic repository. The original is only deleted when deletion
+	// is allowed.
+	StrategyAttic Strategy = "attic"
+)
+
+// ErrDeleteNotAllowed is returned when a strategy that must delete the
+// original repository is used without allowing deletion
+var ErrDeleteNotAllowed = errors.New("strategy requires deleting repositories but deletion was not allowed (pass -allow-delete)")
+
+// ErrMissingTopic is returned when a repository would be deleted without
+// carrying the topic required to approve deletion
+var ErrMissingTopic = errors.New("repository lacks the topic required for deletion")
+
+// ParseStrategy converts a strategy name into a Strategy
+func ParseStrategy(name string) (Strategy, error) {
+	switch s := Strategy(name); s {
+	case StrategyFork, StrategyArchive, StrategyDelete, StrategyAttic:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown strategy %q (valid: %s, %s, %s, %s)", name, StrategyFork, StrategyArchive, StrategyDelete, StrategyAttic)
+}
+
+// RequiresDelete reports whether the strategy cannot work without deleting
+// the original repository
+func (s Strategy) RequiresDelete() bool {
+	return s == StrategyDelete
+}
+
+// DefaultThreshold returns the inactivity threshold in years applied with
+// the strategy when none is given. The more destructive the strategy, the
+// longer a repository must be inactive: a year to mark it archived in place,
+// two to fork it away and three to delete it.
+func (s Strategy) DefaultThreshold() int {
+	switch s {
+	case StrategyArchive:
+		return 1
+	case StrategyDelete:
+		return 3
+	}
+	return 2
+}
+
+// Fork wait defaults. The timeout for a fork grows with the size of the
+// repository, from the base up to the maximum.
+const (
+	DefaultForkTimeout    = 30 * time.Second
+	DefaultForkTimeoutMax = 10 * time.Minute
+	forkTimeoutPerMB      = time.Second
+	forkPollInterval      = 2 * time.Second
+)
+
+// SandboxPrefix labels forks created by TestFork so they are never mistaken
+// for real archives
+const SandboxPrefix = "archiver-test-"
+
+// maxNameAttempts bounds the numbered suffixes tried when a renamed fork
+// would collide with an existing repository
+const maxNameAttempts = 10
+
+// Result describes how a repository was archived
+type Result struct {
+	Strategy     Strategy
+	Namespace    string // namespace holding the archived copy, if any
+	ArchivedName string // name of the archived copy, if any
+	Deleted      bool   // whether the original repository was deleted
+	Visibility   string // visibility of the archived copy, if any
+	AtticPath    string // directory holding the copy in the attic, if any
+
+	// Readers are the collaborators given read access to the archived copy
+	Readers []string
+}
+
 // Archiver handles the repository archiving process
 type Archiver struct {
-	client *github.Client
+	client         *github.Client
+	strategy       Strategy
+	allowDelete    bool
+	forkTimeout    time.Duration
+	forkTimeoutMax time.Duration
+	namePrefix     string
+	nameSuffix     string
+	metrics        *metrics.Counters
+	requiredTopic  string
+	tiered         bool          // only apply the strategy to the deletion tier
+	upstreamPeriod time.Duration // delete forks of upstreams active within it
+	objection      string        // label objecting to a notice
+	notifier       string        // login opening notices
+	protectBranch  bool          // protect the default branch of forks
+	verifySigs     bool          // compare head signatures of forks
+	private        bool          // make forks private
+	copyCollabs    bool          // give collaborators read access to forks
+	clock          util.Clock    // current time for the upstream cutoff
+	attic          attic         // destination of the attic strategy
+
+	atticMu sync.Mutex
+	gitURL  func(owner, repo string) string // clone URL of a repository
+
+	nsMu       sync.Mutex
+	namespaces map[string]*namespaceCheck // verification outcome by namespace
 }
 
 // NewArchiver creates a new repository archiver
 func NewArchiver(client *github.Client) *Archiver {
 	return &Archiver{
-		client: client,
+		client:         client,
+		strategy:       StrategyFork,
+		forkTimeout:    DefaultForkTimeout,
+		forkTimeoutMax: DefaultForkTimeoutMax,
+		clock:          util.SystemClock{},
+		gitURL:         githubURL,
+	}
+}
+
+// SetClock replaces the clock used to decide whether upstreams are active
+func (a *Archiver) SetClock(clock util.Clock) {
+	a.clock = clock
+}
+
+// SetForkTimeout changes the base and maximum time to wait for a fork
+func (a *Archiver) SetForkTimeout(base, max time.Duration) {
+	a.forkTimeout = base
+	a.forkTimeoutMax = max
+}
+
+// SetStrategy changes the strategy used by ArchiveRepository
+func (a *Archiver) SetStrategy(strategy Strategy) {
+	a.strategy = strategy
+}
+
+// SetTiered makes ArchiveRepository apply the configured strategy only to
+// repositories in the deletion tier and archive the rest in place
+func (a *Archiver) SetTiered(tiered bool) {
+	a.tiered = tiered
+}
+
+// SetProtectBranch makes the fork strategy protect the default branch of
+// each fork against force pushes and deletion before archiving it
+func (a *Archiver) SetProtectBranch(protect bool) {
+	a.protectBranch = protect
+}
+
+// SetAllowDelete controls whether original repositories may be deleted
+func (a *Archiver) SetAllowDelete(allow bool) {
+	a.allowDelete = allow
+}
+
+// SetArchiveName sets a prefix and suffix added to the name of archived forks
+func (a *Archiver) SetArchiveName(prefix, suffix string) {
+	a.namePrefix = prefix
+	a.nameSuffix = suffix
+}
+
+// SetRequiredTopic only allows deleting repositories that carry the topic,
+// an approval gate kept in GitHub's own metadata. An empty topic disables
+// the gate.
+func (a *Archiver) SetRequiredTopic(topic string) {
+	a.requiredTopic = topic
+}
+
+// deleteApproved reports whether the repository may be deleted under the
+// required topic gate, logging repositories that may not
+func (a *Archiver) deleteApproved(repo github.Repository) bool {
+	if a.requiredTopic == "" || repo.HasTopic(a.requiredTopic) {
+		return true
 	}
+	logger.Warn("Not deleting %s/%s: missing the required topic %q", repo.Owner, repo.Name, a.requiredTopic)
+	return false
 }
 
-// ArchiveRepository archives a repository by:
+// SetMetrics records archived and failed repositories in counters
+func (a *Archiver) SetMetrics(counters *metrics.Counters) {
+	a.metrics = counters
+}
+
+// ArchiveRepository archives a repository using the configured strategy,
+// adjusted by the deletion tier and the active fork policy
+func (a *Archiver) ArchiveRepository(ctx context.Context, archiveNamespace string, repo github.Repository) (Result, error) {
+	if a.tiered && !repo.DeleteTier {
+		logger.Debug("Repository %s/%s is below the deletion threshold, archiving in place", repo.Owner, repo.Name)
+		return a.ApplyStrategy(ctx, StrategyArchive, archiveNamespace, repo)
+	}
+	strategy, err := a.forkStrategy(ctx, repo, a.strategy)
+	if err != nil {
+		a.metrics.Fail(repo.Owner, repo.Name, err)
+		return Result{Strategy: a.strategy}, err
+	}
+	return a.ApplyStrategy(ctx, strategy, archiveNamespace, repo)
+}
+
+// ApplyStrategy archives a repository using the given strategy
+func (a *Archiver) ApplyStrategy(ctx context.Context, strategy Strategy, archiveNamespace string, repo github.Repository) (Result, error) {
+	result := Result{Strategy: strategy}
+	if strategy.RequiresDelete() && !a.allowDelete {
+		return result, util.NewError(util.UsageError, ErrDeleteNotAllowed)
+	}
+
+	var err error
+	switch strategy {
+	case StrategyFork:
+		err = a.forkAndArchive(ctx, archiveNamespace, repo, &result)
+	case StrategyArchive:
+		err = a.archiveInPlace(ctx, repo.Owner, repo.Name)
+	case StrategyAttic:
+		err = a.moveToAttic(ctx, repo, &result)
+	case StrategyDelete:
+		if !a.deleteApproved(repo) {
+			err = fmt.Errorf("%s/%s: %w %q", repo.Owner, repo.Name, ErrMissingTopic, a.requiredTopic)
+			break
+		}
+		err = a.deleteOnly(ctx, repo.Owner, repo.Name)
+		result.Deleted = true
+	default:
+		err = fmt.Errorf("unknown strategy %q", strategy)
+	}
+
+	if errors.Is(err, github.ErrForkDisabled) || errors.Is(err, ErrMissingTopic) {
+		a.metrics.Inc(metrics.Skipped)
+		return result, err
+	}
+	if err != nil {
+		a.metrics.Fail(repo.Owner, repo.Name, err)
+		return result, err
+	}
+	a.metrics.Inc(metrics.Archived)
+	// Only a deleted original frees storage; archives and kept originals
+	// still count against the account
+	if result.Deleted {
+		a.metrics.Add(metrics.Reclaimed, int64(repo.Size)*1024)
+	}
+	if result.ArchivedName != "" {
+		a.metrics.Add(metrics.BytesBackedUp, int64(repo.Size)*1024)
+	}
+	return result, nil
+}
+
+// forkAndArchive archives a repository by:
 // 1. Creating an archive namespace if it doesn't exist
-// 2. Forking the repository to the archive namespace
-// 3. Deleting the original repository
+// 2. Forking the repository to the archive namespace, renaming the fork if
+// a prefix or suffix is configured
+// 3. Deleting the original repository, if deletion is allowed
 // 4. Setting the archived status to true on the forked repository
-func (a *Archiver) ArchiveRepository(ctx context.Context, owner, archiveNamespace, repo string) error {
+func (a *Archiver) forkAndArchive(ctx context.Context, archiveNamespace string, repository github.Repository, result *Result) error {
+	owner, repo := repository.Owner, repository.Name
 	logger.Debug("Beginning archive process for repository %s/%s", owner, repo)
 
-	// 1. Create archive namespace if it doesn't exist
-	logger.Info("Creating archive namespace %s...", archiveNamespace)
-	err := a.client.CreateArchiveNamespace(ctx, archiveNamespace)
-	if util.ForceProcessing(err) {
-		logger.Error("Failed to create archive namespace %s: %v", archiveNamespace, err)
-		return fmt.Errorf("failed to create archive namespace: %w", err)
+	// 1. Make sure the archive namespace exists; this is normally settled
+	// by the check before archiving starts
+	// A missing namespace fails every repository, so force cannot help
+	err := a.VerifyNamespace(ctx, archiveNamespace)
+	if errors.Is(err, github.ErrNamespaceNotFound) || util.ForceProcessing(err) {
+		logger.Error("Archive namespace %s unavailable: %v", archiveNamespace, err)
+		return fmt.Errorf("failed to verify archive namespace: %w", err)
 	}
 	logger.Debug("Archive namespace %s confirmed", archiveNamespace)
 
-	// 2. Fork the repository to the archive namespace
+	// 2. Fork the repository to the archive namespace, never going further
+	// if it cannot be forked
+	if !repository.AllowForking {
+		logger.Warn("Forking is disabled for %s/%s, skipping", owner, repo)
+		return fmt.Errorf("%s/%s: %w", owner, repo, github.ErrForkDisabled)
+	}
+	// A fork keeps the visibility of its parent, so the fork of a public
+	// repository could never be made private and would be left exposed
+	if a.private && !repository.Private {
+		logger.Error("Not forking %s/%s: it is public, and GitHub does not allow forks to be made private", owner, repo)
+		result.Visibility = visibilityRefused
+		return fmt.Errorf("%s/%s: %w", owner, repo, github.ErrPrivateRefused)
+	}
 	logger.Info("Forking %s/%s to %s...", owner, repo, archiveNamespace)
 	err = a.client.ForkRepository(ctx, owner, repo, archiveNamespace)
 	// don't force continuation on error here.
@@ -50,28 +303,245 @@ func (a *Archiver) ArchiveRepository(ctx context.Context, owner, archiveNamespac
 	logger.Debug("Repository forked successfully")
 
 	// Wait for the fork to be created
-	waitTime := 5 * time.Second
-	logger.Debug("Waiting %v for fork to complete...", waitTime)
-	time.Sleep(waitTime)
+	if err := a.waitForFork(ctx, archiveNamespace, repo, repository.Size); err != nil {
+		logger.Error("Fork %s/%s did not become available: %v", archiveNamespace, repo, err)
+		return err
+	}
+	result.Namespace = archiveNamespace
+	result.ArchivedName = repo
+
+	// Rename the fork to mark it as an archive
+	archivedName, err := a.renameFork(ctx, archiveNamespace, repo, a.namePrefix, a.nameSuffix)
+	if err != nil {
+		return err
+	}
+	result.ArchivedName = archivedName
+
+	result.Visibility = visibility(repository.Private)
+
+	// Collaborators are listed from the original, so copy them before it
+	// can be deleted
+	if a.copyCollabs {
+		readers, err := a.copyCollaborators(ctx, owner, repo, archiveNamespace, archivedName)
+		result.Readers = readers
+		if err != nil {
+			return err
+		}
+	}
+
+	// Make sure signed history carried over while the original still exists
+	if a.verifySigs {
+		err := a.verifySignatures(ctx, owner, repo, archiveNamespace, archivedName, repository.DefaultBranch)
+		if err != nil {
+			logger.Error("Signature verification of %s/%s failed: %v", owner, repo, err)
+			return err
+		}
+	}
+
+	// Archived repositories are read-only, so protect the branch first
+	if a.protectBranch {
+		if err := a.protectFork(ctx, archiveNamespace, archivedName, repository.DefaultBranch); err != nil {
+			return err
+		}
+	}
 
 	// 3. Delete the original repository
-	logger.Info("Deleting original repository %s/%s...", owner, repo)
-	err = a.client.DeleteRepository(ctx, owner, repo)
-	if util.ForceProcessing(err) {
-		logger.Error("Failed to delete original repository %s/%s: %v", owner, repo, err)
-		return fmt.Errorf("failed to delete original repository: %w", err)
+	if a.allowDelete && a.deleteApproved(repository) {
+		logger.Info("Deleting original repository %s/%s...", owner, repo)
+		err = a.client.DeleteRepository(ctx, owner, repo)
+		if err != nil {
+			logger.Error("Failed to delete original repository %s/%s: %v", owner, repo, err)
+			return fmt.Errorf("failed to delete original repository: %w", err)
+		}
+		result.Deleted = true
+		logger.Debug("Original repository deleted")
+	} else if !a.allowDelete {
+		logger.Info("Keeping original repository %s/%s (pass -allow-delete to remove it)", owner, repo)
 	}
-	logger.Debug("Original repository deleted")
 
 	// 4. Set the archived status to true on the forked repository
-	logger.Info("Setting archived status on %s/%s...", archiveNamespace, repo)
-	err = a.client.SetArchiveStatus(ctx, archiveNamespace, repo, true)
-	if util.ForceProcessing(err) {
-		logger.Error("Failed to set archived status on %s/%s: %v", archiveNamespace, repo, err)
+	logger.Info("Setting archived status on %s/%s...", archiveNamespace, archivedName)
+	err = a.client.SetArchiveStatus(ctx, archiveNamespace, archivedName, true)
+	if err != nil {
+		logger.Error("Failed to set archived status on %s/%s: %v", archiveNamespace, archivedName, err)
 		return fmt.Errorf("failed to set archived status: %w", err)
 	}
 	logger.Debug("Archive status set successfully")
 
-	logger.Info("Repository %s successfully archived to %s/%s", repo, archiveNamespace, repo)
+	logger.Info("Repository %s successfully archived to %s/%s", repo, archiveNamespace, archivedName)
 	return nil
 }
+
+// SetArchivePrivate makes the fork strategy keep every archive private.
+// Forks of private repositories are private already, and public repositories
+// are refused before they are forked, since forks cannot change visibility.
+func (a *Archiver) SetArchivePrivate(private bool) {
+	a.private = private
+}
+
+// visibilityRefused is the visibility recorded for a public repository that
+// was not forked because its archive could not have been private
+const visibilityRefused = "private refused"
+
+// visibility names the visibility of a repository
+func visibility(private bool) string {
+	if private {
+		return "private"
+	}
+	return "public"
+}
+
+// protectFork protects the default branch of a fork against force pushes
+// and deletion. Empty repositories have no branch to protect.
+func (a *Archiver) protectFork(ctx context.Context, namespace, name, branch string) error {
+	if branch == "" {
+		logger.Info("Fork %s/%s has no default branch, not protecting it", namespace, name)
+		return nil
+	}
+	logger.Info("Protecting branch %s of %s/%s...", branch, namespace, name)
+	protected, err := a.client.ProtectBranch(ctx, namespace, name, branch)
+	if err != nil {
+		return err
+	}
+	if !protected {
+		logger.Warn("Branch protection is unavailable for %s/%s, relying on the archived flag alone", namespace, name)
+	}
+	return nil
+}
+
+// TestFork exercises the fork flow against a sandbox namespace without
+// touching the original repository: it forks the repository, waits for the
+// fork, labels it with SandboxPrefix and, if cleanup is set, deletes the test
+// fork again. It returns the name of the test fork.
+func (a *Archiver) TestFork(ctx context.Context, sandbox string, repository github.Repository, cleanup bool) (string, error) {
+	owner, repo := repository.Owner, repository.Name
+
+	if err := a.VerifyNamespace(ctx, sandbox); err != nil {
+		return "", fmt.Errorf("sandbox namespace unavailable: %w", err)
+	}
+
+	// A pre-existing repository could not be told apart from the test fork
+	exists, err := a.client.RepositoryExists(ctx, sandbox, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to check %s/%s: %w", sandbox, repo, err)
+	}
+	if exists {
+		return "", fmt.Errorf("%s/%s already exists; remove it before testing the fork", sandbox, repo)
+	}
+
+	if !repository.AllowForking {
+		return "", fmt.Errorf("%s/%s: %w", owner, repo, github.ErrForkDisabled)
+	}
+	logger.Info("Test forking %s/%s to %s...", owner, repo, sandbox)
+	if err := a.client.ForkRepository(ctx, owner, repo, sandbox); err != nil {
+		return "", fmt.Errorf("failed to fork repository: %w", err)
+	}
+	if err := a.waitForFork(ctx, sandbox, repo, repository.Size); err != nil {
+		return "", err
+	}
+
+	testName, err := a.renameFork(ctx, sandbox, repo, SandboxPrefix, "")
+	if err != nil {
+		return repo, err
+	}
+	logger.Info("Test fork %s/%s verified", sandbox, testName)
+
+	if cleanup {
+		logger.Info("Deleting test fork %s/%s...", sandbox, testName)
+		if err := a.client.DeleteRepository(ctx, sandbox, testName); err != nil {
+			return testName, fmt.Errorf("failed to delete test fork: %w", err)
+		}
+	}
+	return testName, nil
+}
+
+// renameFork applies a prefix and suffix to a fork, adding a numbered suffix
+// if the name is already taken, and returns the final name
+func (a *Archiver) renameFork(ctx context.Context, namespace, repo, prefix, suffix string) (string, error) {
+	if prefix == "" && suffix == "" {
+		return repo, nil
+	}
+
+	base := prefix + repo + suffix
+	for attempt := 1; attempt <= maxNameAttempts; attempt++ {
+		name := base
+		if attempt > 1 {
+			name = fmt.Sprintf("%s-%d", base, attempt)
+		}
+
+		exists, err := a.client.RepositoryExists(ctx, namespace, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to check name %s/%s: %w", namespace, name, err)
+		}
+		if exists {
+			logger.Debug("Repository %s/%s already exists, trying another name", namespace, name)
+			continue
+		}
+
+		logger.Info("Renaming fork %s/%s to %s...", namespace, repo, name)
+		if err := a.client.RenameRepository(ctx, namespace, repo, name); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("no free name for %s in %s after %d attempts", base, namespace, maxNameAttempts)
+}
+
+// archiveInPlace marks the original repository archived without copying it
+func (a *Archiver) archiveInPlace(ctx context.Context, owner, repo string) error {
+	logger.Info("Setting archived status on %s/%s...", owner, repo)
+	err := a.client.SetArchiveStatus(ctx, owner, repo, true)
+	if err != nil {
+		logger.Error("Failed to set archived status on %s/%s: %v", owner, repo, err)
+		return fmt.Errorf("failed to set archived status: %w", err)
+	}
+
+	logger.Info("Repository %s/%s successfully archived in place", owner, repo)
+	return nil
+}
+
+// deleteOnly deletes the original repository without keeping a copy
+func (a *Archiver) deleteOnly(ctx context.Context, owner, repo string) error {
+	logger.Info("Deleting repository %s/%s...", owner, repo)
+	err := a.client.DeleteRepository(ctx, owner, repo)
+	if err != nil {
+		logger.Error("Failed to delete repository %s/%s: %v", owner, repo, err)
+		return fmt.Errorf("failed to delete repository: %w", err)
+	}
+
+	logger.Info("Repository %s/%s successfully deleted", owner, repo)
+	return nil
+}
+
+// forkWaitTimeout scales the fork timeout with the repository size in kilobytes
+func (a *Archiver) forkWaitTimeout(sizeKB int) time.Duration {
+	timeout := a.forkTimeout + time.Duration(sizeKB/1024)*forkTimeoutPerMB
+	if timeout > a.forkTimeoutMax {
+		timeout = a.forkTimeoutMax
+	}
+	return timeout
+}
+
+// waitForFork polls until the fork exists or the size-scaled timeout expires
+func (a *Archiver) waitForFork(ctx context.Context, namespace, repo string, sizeKB int) error {
+	timeout := a.forkWaitTimeout(sizeKB)
+	logger.Debug("Waiting up to %v for fork of %d KB repository to complete...", timeout, sizeKB)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		exists, err := a.client.RepositoryExists(ctx, namespace, repo)
+		if err != nil {
+			logger.Debug("Checking fork %s/%s failed: %v", namespace, repo, err)
+		} else if exists {
+			logger.Debug("Fork %s/%s is available", namespace, repo)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for fork %s/%s", timeout, namespace, repo)
+		}
+		if err := util.SleepCtx(ctx, forkPollInterval); err != nil {
+			return fmt.Errorf("interrupted while waiting for fork: %w", err)
+		}
+	}
+}
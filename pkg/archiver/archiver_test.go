@@ -0,0 +1,104 @@
+package archiver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/metrics"
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// newFixtureArchiver creates an archiver whose client answers from an empty
+// fixture directory, where every account and repository is missing, and
+// counts the API requests it sends
+func newFixtureArchiver(t *testing.T) (*Archiver, *metrics.Counters) {
+	t.Helper()
+	client, err := github.NewFixtureClient(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counters := metrics.New()
+	client.SetMetrics(counters)
+	return NewArchiver(client), counters
+}
+
+func TestMissingNamespaceAbortsUnderForce(t *testing.T) {
+	defer func(force bool) { util.FORCE_PROCESSING = force }(util.FORCE_PROCESSING)
+	util.FORCE_PROCESSING = true
+
+	a, counters := newFixtureArchiver(t)
+	repo := github.Repository{Owner: "acme", Name: "tool", AllowForking: true}
+	_, err := a.ArchiveRepository(context.Background(), "acme-archive", repo)
+	if !errors.Is(err, github.ErrNamespaceNotFound) {
+		t.Fatalf("ArchiveRepository() = %v, want ErrNamespaceNotFound", err)
+	}
+	if kind, _ := util.KindOf(err); kind != util.UsageError {
+		t.Errorf("error kind = %v, want %v", kind, util.UsageError)
+	}
+	// Looking the namespace up as an organization and as a user, and no fork
+	if calls := counters.Snapshot().APICalls; calls != 2 {
+		t.Errorf("%d API requests sent, want only the 2 namespace lookups", calls)
+	}
+}
+
+// writeFixtures writes API fixtures, keyed by request path, into dir
+func writeFixtures(t *testing.T, dir string, fixtures map[string]string) {
+	t.Helper()
+	for path, body := range fixtures {
+		name := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReclaimedCountsDeletedOriginals(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    Strategy
+		allowDelete bool
+		reclaimed   int64
+	}{
+		{"fork keeping the original", StrategyFork, false, 0},
+		{"fork deleting the original", StrategyFork, true, 2048 * 1024},
+		{"archive in place", StrategyArchive, false, 0},
+		{"delete", StrategyDelete, true, 2048 * 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixtures(t, dir, map[string]string{
+				"orgs/acme-archive.json":       `{"login":"acme-archive"}`,
+				"repos/acme/tool.json":         `{"name":"tool","owner":{"login":"acme"}}`,
+				"repos/acme-archive/tool.json": `{"name":"tool","owner":{"login":"acme-archive"},"fork":true,"parent":{"full_name":"acme/tool"}}`,
+			})
+			client, err := github.NewFixtureClient(dir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			counters := metrics.New()
+			a := NewArchiver(client)
+			a.SetMetrics(counters)
+			a.SetAllowDelete(tt.allowDelete)
+
+			repo := github.Repository{Owner: "acme", Name: "tool", AllowForking: true, Size: 2048}
+			if _, err := a.ApplyStrategy(context.Background(), tt.strategy, "acme-archive", repo); err != nil {
+				t.Fatalf("ApplyStrategy: %v", err)
+			}
+			stats := counters.Snapshot()
+			if stats.Archived != 1 {
+				t.Errorf("archived = %d, want 1", stats.Archived)
+			}
+			if stats.Reclaimed != tt.reclaimed {
+				t.Errorf("reclaimed = %d bytes, want %d", stats.Reclaimed, tt.reclaimed)
+			}
+		})
+	}
+}
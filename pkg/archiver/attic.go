@@ -0,0 +1,237 @@
+package archiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// ErrEmptySource is returned when a repository without commits is moved to
+// the attic, since it has no tree to preserve
+var ErrEmptySource = errors.New("repository has no commits to move to the attic")
+
+// atticAuthor is the identity of the commits importing sources into the attic
+const (
+	atticAuthorName  = "github-archiver"
+	atticAuthorEmail = "github-archiver@users.noreply.github.com"
+)
+
+// attic is the destination of the attic strategy
+type attic struct {
+	owner, name string
+	shallow     bool   // import only the head of each source, without history
+	token       string // authenticates git over HTTPS
+}
+
+// SetAttic configures the single repository that the attic strategy commits
+// each source into, under owner/repo/. A shallow attic imports only the head
+// of each source; otherwise the source's history is merged in as well.
+func (a *Archiver) SetAttic(owner, name string, shallow bool, token string) {
+	a.attic = attic{owner: owner, name: name, shallow: shallow, token: token}
+}
+
+// VerifyAttic checks that the attic repository exists
+func (a *Archiver) VerifyAttic(ctx context.Context) error {
+	if _, err := a.client.GetRepository(ctx, a.attic.owner, a.attic.name); err != nil {
+		return fmt.Errorf("attic repository unavailable: %w", err)
+	}
+	return nil
+}
+
+// githubURL is the HTTPS clone URL of a repository on GitHub
+func githubURL(owner, repo string) string {
+	return fmt.Sprintf("https://github.com/%s/%s.git", owner, repo)
+}
+
+// moveToAttic archives a repository by:
+// 1. Fetching the source and the head of the attic into a scratch repository
+// 2. Choosing owner/repo/ in the attic, adding a numbered suffix if taken
+// 3. Committing the source's tree there on top of the attic's head, with the
+// source's history as a second parent unless the attic is shallow
+// 4. Pushing the commit to the attic's default branch
+// 5. Deleting the original repository, if deletion is allowed
+func (a *Archiver) moveToAttic(ctx context.Context, repository github.Repository, result *Result) error {
+	owner, repo := repository.Owner, repository.Name
+	atticName := a.attic.owner + "/" + a.attic.name
+
+	// Commits to the attic are made one at a time, each on top of the last
+	a.atticMu.Lock()
+	defer a.atticMu.Unlock()
+
+	target, err := a.client.GetRepository(ctx, a.attic.owner, a.attic.name)
+	if err != nil {
+		return fmt.Errorf("attic repository unavailable: %w", err)
+	}
+	branch := target.DefaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+
+	dir, err := os.MkdirTemp("", "github-archiver-attic-")
+	if err != nil {
+		return fmt.Errorf("failed to create attic workspace: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	g := &git{dir: dir, token: a.attic.token}
+	if _, err := g.run(ctx, "init", "--quiet"); err != nil {
+		return err
+	}
+
+	// 1. Fetch the source and the attic's head; an empty attic has no head
+	sourceURL, atticURL := a.gitURL(owner, repo), a.gitURL(a.attic.owner, a.attic.name)
+	if head, err := g.run(ctx, "ls-remote", sourceURL, "HEAD"); err != nil {
+		return fmt.Errorf("failed to read %s/%s: %w", owner, repo, err)
+	} else if head == "" {
+		return fmt.Errorf("%s/%s: %w", owner, repo, ErrEmptySource)
+	}
+	logger.Info("Fetching %s/%s for the attic...", owner, repo)
+	fetch := []string{"fetch", "--quiet", "--no-tags"}
+	if a.attic.shallow {
+		fetch = append(fetch, "--depth=1")
+	}
+	if _, err := g.run(ctx, append(fetch, sourceURL, "+HEAD:refs/source")...); err != nil {
+		return fmt.Errorf("failed to fetch %s/%s: %w", owner, repo, err)
+	}
+	sourceSHA, err := g.run(ctx, "rev-parse", "refs/source")
+	if err != nil {
+		return err
+	}
+	head, err := g.run(ctx, "ls-remote", atticURL, "refs/heads/"+branch)
+	if err != nil {
+		return fmt.Errorf("failed to read attic %s: %w", atticName, err)
+	}
+	empty := head == ""
+	if !empty {
+		if _, err := g.run(ctx, "fetch", "--quiet", "--no-tags", "--depth=1", atticURL, "+refs/heads/"+branch+":refs/attic"); err != nil {
+			return fmt.Errorf("failed to fetch attic %s: %w", atticName, err)
+		}
+	}
+
+	// 2. Find a free directory for the source
+	path, err := atticPath(ctx, g, owner, repo, empty)
+	if err != nil {
+		return err
+	}
+
+	// 3. Build the attic's tree with the source under its directory
+	if empty {
+		_, err = g.run(ctx, "read-tree", "--empty")
+	} else {
+		_, err = g.run(ctx, "read-tree", "refs/attic")
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := g.run(ctx, "read-tree", "--prefix="+path+"/", "refs/source"); err != nil {
+		return err
+	}
+	tree, err := g.run(ctx, "write-tree")
+	if err != nil {
+		return err
+	}
+	commitArgs := []string{"commit-tree", tree, "-m", fmt.Sprintf("Move %s/%s to %s/", owner, repo, path),
+		"-m", fmt.Sprintf("Imported from %s at %s.", githubURL(owner, repo), sourceSHA)}
+	if !empty {
+		commitArgs = append(commitArgs, "-p", "refs/attic")
+	}
+	// A shallow source has no history the attic could take in
+	if !a.attic.shallow {
+		commitArgs = append(commitArgs, "-p", "refs/source")
+	}
+	commit, err := g.run(ctx, commitArgs...)
+	if err != nil {
+		return err
+	}
+
+	// 4. Push without force, so a concurrent change to the attic is never lost
+	logger.Info("Committing %s/%s to %s under %s/...", owner, repo, atticName, path)
+	if _, err := g.run(ctx, "push", "--quiet", atticURL, commit+":refs/heads/"+branch); err != nil {
+		logger.Error("Failed to push %s/%s to the attic %s: %v", owner, repo, atticName, err)
+		return fmt.Errorf("failed to push to attic %s: %w", atticName, err)
+	}
+	result.Namespace = a.attic.owner
+	result.ArchivedName = a.attic.name
+	result.AtticPath = path
+	result.Visibility = visibility(target.Private)
+
+	// 5. Delete the original repository
+	if a.allowDelete && a.deleteApproved(repository) {
+		logger.Info("Deleting original repository %s/%s...", owner, repo)
+		if err := a.client.DeleteRepository(ctx, owner, repo); err != nil {
+			logger.Error("Failed to delete original repository %s/%s: %v", owner, repo, err)
+			return fmt.Errorf("failed to delete original repository: %w", err)
+		}
+		result.Deleted = true
+	} else if !a.allowDelete {
+		logger.Info("Keeping original repository %s/%s (pass -allow-delete to remove it)", owner, repo)
+	}
+
+	logger.Info("Repository %s/%s successfully moved to %s under %s/", owner, repo, atticName, path)
+	return nil
+}
+
+// atticPath returns owner/repo, or the first free numbered variant of it if
+// the attic already has that directory
+func atticPath(ctx context.Context, g *git, owner, repo string, empty bool) (string, error) {
+	base := owner + "/" + repo
+	if empty {
+		return base, nil
+	}
+	for attempt := 1; attempt <= maxNameAttempts; attempt++ {
+		path := base
+		if attempt > 1 {
+			path = fmt.Sprintf("%s-%d", base, attempt)
+		}
+		taken, err := g.run(ctx, "ls-tree", "--name-only", "refs/attic", "--", path)
+		if err != nil {
+			return "", err
+		}
+		if taken == "" {
+			return path, nil
+		}
+		logger.Debug("The attic already has %s/, trying another directory", path)
+	}
+	return "", fmt.Errorf("no free directory for %s in the attic after %d attempts", base, maxNameAttempts)
+}
+
+// git runs git commands in a scratch repository, authenticating HTTPS
+// requests with a token passed through the environment, never the command
+// line
+type git struct {
+	dir   string
+	token string
+}
+
+// run runs a git command and returns its trimmed output
+func (g *git) run(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = g.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_AUTHOR_NAME="+atticAuthorName, "GIT_AUTHOR_EMAIL="+atticAuthorEmail,
+		"GIT_COMMITTER_NAME="+atticAuthorName, "GIT_COMMITTER_EMAIL="+atticAuthorEmail,
+	)
+	if g.token != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + g.token))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.https://github.com/.extraheader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials,
+		)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,144 @@
+package archiver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+)
+
+// gitIn runs a git command in dir and returns its trimmed output
+func gitIn(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitFiles creates a repository at dir holding files in a single commit
+// on main
+func commitFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	gitIn(t, "", "init", "--quiet", "--initial-branch=main", dir)
+	for path, body := range files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitIn(t, dir, "add", ".")
+	gitIn(t, dir, "commit", "--quiet", "-m", "initial")
+}
+
+// newAtticArchiver creates an archiver that moves repositories into the
+// attic acme-archive/attic, allowing deletion. Git reaches each owner/repo
+// as a repository under root; the API answers from fixtures.
+func newAtticArchiver(t *testing.T, root string, shallow bool) *Archiver {
+	t.Helper()
+	dir := t.TempDir()
+	writeFixtures(t, dir, map[string]string{
+		"repos/acme-archive/attic.json": `{"name":"attic","owner":{"login":"acme-archive"},"default_branch":"main","private":true}`,
+	})
+	client, err := github.NewFixtureClient(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewArchiver(client)
+	a.SetAllowDelete(true)
+	a.SetAttic("acme-archive", "attic", shallow, "")
+	a.gitURL = func(owner, repo string) string {
+		return "file://" + filepath.Join(root, owner, repo)
+	}
+	return a
+}
+
+func TestMoveToAttic(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tests := []struct {
+		name    string
+		attic   map[string]string // files already in the attic, if any
+		shallow bool
+		path    string
+		parents int
+	}{
+		{"empty attic", nil, false, "acme/tool", 1},
+		{"history merged in", map[string]string{"other/lib/README": "lib"}, false, "acme/tool", 2},
+		{"shallow", map[string]string{"other/lib/README": "lib"}, true, "acme/tool", 1},
+		{"directory taken", map[string]string{"acme/tool/README": "older tool"}, true, "acme/tool-2", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			commitFiles(t, filepath.Join(root, "acme", "tool"), map[string]string{"README": "tool"})
+			atticDir := filepath.Join(root, "acme-archive", "attic")
+			gitIn(t, root, "init", "--quiet", "--bare", "--initial-branch=main", atticDir)
+			if tt.attic != nil {
+				seed := filepath.Join(root, "seed")
+				commitFiles(t, seed, tt.attic)
+				gitIn(t, seed, "push", "--quiet", atticDir, "main")
+			}
+
+			a := newAtticArchiver(t, root, tt.shallow)
+			repo := github.Repository{Owner: "acme", Name: "tool"}
+			result, err := a.ApplyStrategy(context.Background(), StrategyAttic, "", repo)
+			if err != nil {
+				t.Fatalf("ApplyStrategy: %v", err)
+			}
+			if result.AtticPath != tt.path || result.Namespace != "acme-archive" || result.ArchivedName != "attic" {
+				t.Errorf("result = %+v, want acme-archive/attic under %s", result, tt.path)
+			}
+			if !result.Deleted {
+				t.Error("original kept, want it deleted")
+			}
+			if got := gitIn(t, atticDir, "show", "main:"+tt.path+"/README"); got != "tool" {
+				t.Errorf("%s/README = %q, want the source's", tt.path, got)
+			}
+			for path, body := range tt.attic {
+				if got := gitIn(t, atticDir, "show", "main:"+path); got != body {
+					t.Errorf("%s = %q, want %q kept", path, got, body)
+				}
+			}
+			parents := strings.Fields(gitIn(t, atticDir, "log", "-1", "--format=%P", "main"))
+			if len(parents) != tt.parents {
+				t.Errorf("attic head has %d parents, want %d", len(parents), tt.parents)
+			}
+		})
+	}
+}
+
+func TestMoveToAtticEmptySource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	gitIn(t, root, "init", "--quiet", "--bare", filepath.Join(root, "acme", "tool"))
+	gitIn(t, root, "init", "--quiet", "--bare", filepath.Join(root, "acme-archive", "attic"))
+
+	a := newAtticArchiver(t, root, false)
+	repo := github.Repository{Owner: "acme", Name: "tool"}
+	result, err := a.ApplyStrategy(context.Background(), StrategyAttic, "", repo)
+	if !errors.Is(err, ErrEmptySource) {
+		t.Fatalf("ApplyStrategy() = %v, want %v", err, ErrEmptySource)
+	}
+	if result.Deleted {
+		t.Error("empty original deleted without an attic copy")
+	}
+}
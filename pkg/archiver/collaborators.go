@@ -0,0 +1,49 @@
+package archiver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// SetCopyCollaborators makes the fork strategy give the direct
+// collaborators of each original read access to its fork, so they keep
+// access to the archive
+func (a *Archiver) SetCopyCollaborators(copy bool) {
+	a.copyCollabs = copy
+}
+
+// copyCollaborators gives the direct collaborators of a repository read
+// access to its fork and returns those added or invited. Collaborators
+// GitHub refuses to add, such as outside collaborators barred by the
+// archive organization's policy, are skipped with a warning.
+func (a *Archiver) copyCollaborators(ctx context.Context, owner, repo, namespace, fork string) ([]string, error) {
+	logins, err := a.client.ListDirectCollaborators(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	if len(logins) == 0 {
+		logger.Info("Repository %s/%s has no direct collaborators to copy", owner, repo)
+		return nil, nil
+	}
+
+	logger.Info("Giving %d collaborators of %s/%s read access to %s/%s...", len(logins), owner, repo, namespace, fork)
+	var copied []string
+	for _, login := range logins {
+		invited, err := a.client.AddReader(ctx, namespace, fork, login)
+		if errors.Is(err, github.ErrCollaboratorRefused) {
+			logger.Warn("Could not add %s to %s/%s: %v", login, namespace, fork, err)
+			continue
+		}
+		if err != nil {
+			return copied, err
+		}
+		if invited {
+			logger.Debug("Invited %s to %s/%s", login, namespace, fork)
+		}
+		copied = append(copied, login)
+	}
+	return copied, nil
+}
@@ -0,0 +1,69 @@
+package archiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// SetDeleteActiveForks makes ArchiveRepository delete, rather than archive,
+// forks whose upstream was pushed to within the given period, since their
+// code lives on upstream. A period of 0 disables the policy.
+func (a *Archiver) SetDeleteActiveForks(period time.Duration) {
+	a.upstreamPeriod = period
+}
+
+// upstreamActive fetches the upstream of a fork and reports whether it was
+// pushed to within the configured period, and its full name. A fork whose
+// upstream is gone or hidden has no active upstream.
+func (a *Archiver) upstreamActive(ctx context.Context, repo github.Repository) (bool, string, error) {
+	if repo.Parent == "" {
+		fetched, err := a.client.GetRepository(ctx, repo.Owner, repo.Name)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to look up the upstream: %w", err)
+		}
+		repo = fetched
+	}
+	owner, name, ok := strings.Cut(repo.Parent, "/")
+	if !ok {
+		return false, "", nil
+	}
+
+	upstream, err := a.client.GetRepository(ctx, owner, name)
+	if errors.Is(err, github.ErrNotFound) {
+		return false, repo.Parent, nil
+	}
+	if err != nil {
+		return false, repo.Parent, fmt.Errorf("failed to check upstream %s: %w", repo.Parent, err)
+	}
+	active := !upstream.IsArchived && upstream.PushedAt.After(a.clock.Now().Add(-a.upstreamPeriod))
+	logger.Debug("Upstream %s of %s/%s last pushed %s (archived: %v)",
+		repo.Parent, repo.Owner, repo.Name, upstream.PushedAt.Format("2006-01-02"), upstream.IsArchived)
+	return active, repo.Parent, nil
+}
+
+// forkStrategy returns the strategy for a repository under the active fork
+// policy: delete forks of active upstreams, and the given strategy otherwise
+func (a *Archiver) forkStrategy(ctx context.Context, repo github.Repository, strategy Strategy) (Strategy, error) {
+	if a.upstreamPeriod == 0 || !repo.IsFork || strategy == StrategyDelete {
+		return strategy, nil
+	}
+	active, upstream, err := a.upstreamActive(ctx, repo)
+	switch {
+	case err != nil:
+		return strategy, err
+	case upstream == "":
+		logger.Info("Fork %s/%s has no visible upstream, applying the %s strategy", repo.Owner, repo.Name, strategy)
+	case active:
+		logger.Info("Fork %s/%s of %s: upstream is active, deleting the fork instead of archiving it", repo.Owner, repo.Name, upstream)
+		return StrategyDelete, nil
+	default:
+		logger.Info("Fork %s/%s of %s: upstream is inactive or gone, applying the %s strategy", repo.Owner, repo.Name, upstream, strategy)
+	}
+	return strategy, nil
+}
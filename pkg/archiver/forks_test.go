@@ -0,0 +1,67 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+)
+
+// fixedClock is a Clock stopped at one instant
+type fixedClock time.Time
+
+// Now returns the instant the clock is stopped at
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestForkStrategyUpstreamBoundary(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	period := 365 * 24 * time.Hour
+	cutoff := now.Add(-period)
+
+	tests := []struct {
+		name     string
+		pushed   time.Time
+		archived bool
+		want     Strategy
+	}{
+		{"pushed a second after the cutoff", cutoff.Add(time.Second), false, StrategyDelete},
+		{"pushed on the cutoff", cutoff, false, StrategyFork},
+		{"pushed a second before the cutoff", cutoff.Add(-time.Second), false, StrategyFork},
+		{"recently pushed but archived", now, true, StrategyFork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, "repos", "upstream"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			upstream := fmt.Sprintf(`{"name":"tool","owner":{"login":"upstream"},"pushed_at":%q,"archived":%v}`,
+				tt.pushed.Format(time.RFC3339), tt.archived)
+			if err := os.WriteFile(filepath.Join(dir, "repos", "upstream", "tool.json"), []byte(upstream), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			client, err := github.NewFixtureClient(dir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a := NewArchiver(client)
+			a.SetClock(fixedClock(now))
+			a.SetDeleteActiveForks(period)
+			fork := github.Repository{Owner: "acme", Name: "tool", IsFork: true, Parent: "upstream/tool"}
+			got, err := a.forkStrategy(context.Background(), fork, StrategyFork)
+			if err != nil {
+				t.Fatalf("forkStrategy: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("forkStrategy() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// ExportIssues stores a markdown summary of the open issues and pull requests
+// of a repository in a secret gist and returns its URL. Repositories without
+// open issues get no gist and an empty URL.
+func (a *Archiver) ExportIssues(ctx context.Context, repo github.Repository) (string, error) {
+	issues, err := a.client.ListOpenIssues(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(issues) == 0 {
+		logger.Debug("No open issues in %s/%s, skipping gist", repo.Owner, repo.Name)
+		return "", nil
+	}
+
+	logger.Info("Exporting %d open issues and pull requests of %s/%s to a gist...", len(issues), repo.Owner, repo.Name)
+	description := fmt.Sprintf("Open issues and pull requests of %s/%s at archive time", repo.Owner, repo.Name)
+	filename := fmt.Sprintf("%s-%s-issues.md", repo.Owner, repo.Name)
+	return a.client.CreateGist(ctx, description, filename, issueSummary(repo, issues))
+}
+
+// issueSummary renders issues as a markdown document
+func issueSummary(repo github.Repository, issues []github.Issue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Open issues and pull requests of %s/%s\n\n", repo.Owner, repo.Name)
+	for _, issue := range issues {
+		kind := "Issue"
+		if issue.IsPullRequest {
+			kind = "Pull request"
+		}
+		fmt.Fprintf(&b, "- %s [#%d](%s): %s\n", kind, issue.Number, issue.URL, issue.Title)
+		fmt.Fprintf(&b, "  by @%s, opened %s, updated %s, %d comments\n",
+			issue.Author, issue.CreatedAt.Format("2006-01-02"), issue.UpdatedAt.Format("2006-01-02"), issue.Comments)
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package archiver
+
+import (
+	"context"
+	"sync"
+)
+
+// namespaceCheck holds the outcome of verifying one archive namespace
+type namespaceCheck struct {
+	once sync.Once
+	err  error
+}
+
+// VerifyNamespace checks that an archive namespace exists. Each namespace
+// is verified at most once per archiver: the first caller makes the check,
+// and every later or concurrent caller waits for and shares its outcome,
+// including a failure to check. Callers verify every namespace before
+// archiving, so the per-repository path finds the outcome already settled.
+func (a *Archiver) VerifyNamespace(ctx context.Context, namespace string) error {
+	a.nsMu.Lock()
+	if a.namespaces == nil {
+		a.namespaces = make(map[string]*namespaceCheck)
+	}
+	check, ok := a.namespaces[namespace]
+	if !ok {
+		check = &namespaceCheck{}
+		a.namespaces[namespace] = check
+	}
+	a.nsMu.Unlock()
+
+	check.once.Do(func() {
+		check.err = a.client.CreateArchiveNamespace(ctx, namespace)
+	})
+	return check.err
+}
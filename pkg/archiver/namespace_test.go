@@ -0,0 +1,44 @@
+package archiver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/metrics"
+)
+
+func TestVerifyNamespaceOnce(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "orgs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "orgs", "acme-archive.json"), []byte(`{"login":"acme-archive"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client, err := github.NewFixtureClient(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counters := metrics.New()
+	client.SetMetrics(counters)
+	a := NewArchiver(client)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.VerifyNamespace(context.Background(), "acme-archive"); err != nil {
+				t.Errorf("VerifyNamespace() = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls := counters.Snapshot().APICalls; calls != 1 {
+		t.Errorf("%d verification requests sent, want 1", calls)
+	}
+}
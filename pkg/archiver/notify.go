@@ -0,0 +1,91 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// NotifyTitle is the title of the issue announcing that a repository will
+// be archived. Later runs find the notice by this title.
+const NotifyTitle = "This repository will be archived"
+
+// DefaultObjectionLabel is the label that cancels archiving when added to
+// the notice issue
+const DefaultObjectionLabel = "keep-repository"
+
+// notifyText is the body of the notice issue
+const notifyText = `%sThis repository has had no activity since %s and will be archived after %s.
+
+To keep it, add the %q label to this issue or close it before then.
+`
+
+// Notice is the state of the archiving notice of a repository
+type Notice struct {
+	Issue     github.Issue
+	Found     bool      // whether the repository has a notice
+	Objection string    // why archiving was objected to, if it was
+	GraceEnds time.Time // when archiving may proceed
+}
+
+// Notify opens an issue announcing that the repository will be archived
+// after the grace period, mentioning its admins
+func (a *Archiver) Notify(ctx context.Context, repo github.Repository, grace time.Duration) (github.Issue, error) {
+	logger.Info("Notifying %s/%s of archiving in %v...", repo.Owner, repo.Name, grace)
+
+	admins, err := a.client.ListAdmins(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return github.Issue{}, err
+	}
+	var mentions string
+	if len(admins) > 0 {
+		mentions = "@" + strings.Join(admins, " @") + "\n\n"
+	}
+	body := fmt.Sprintf(notifyText, mentions,
+		repo.LastActivity.Format("2006-01-02"), a.clock.Now().Add(grace).Format("2006-01-02"), a.objectionLabel())
+	return a.client.CreateIssue(ctx, repo.Owner, repo.Name, NotifyTitle, body)
+}
+
+// CheckNotice finds the notice issue of a repository and reports whether
+// archiving was objected to, by adding the objection label or closing the
+// notice, and when its grace period ends
+func (a *Archiver) CheckNotice(ctx context.Context, repo github.Repository, grace time.Duration) (Notice, error) {
+	if a.notifier == "" {
+		login, err := a.client.AuthenticatedUser(ctx)
+		if err != nil {
+			return Notice{}, err
+		}
+		a.notifier = login
+	}
+
+	issue, found, err := a.client.FindIssue(ctx, repo.Owner, repo.Name, a.notifier, NotifyTitle)
+	if err != nil || !found {
+		return Notice{}, err
+	}
+	notice := Notice{Issue: issue, Found: true, GraceEnds: issue.CreatedAt.Add(grace)}
+	switch {
+	case issue.HasLabel(a.objectionLabel()):
+		notice.Objection = fmt.Sprintf("labeled %s", a.objectionLabel())
+	case issue.State == "closed":
+		notice.Objection = "notice closed"
+	}
+	return notice, nil
+}
+
+// SetObjectionLabel changes the label that cancels archiving when added to
+// the notice issue
+func (a *Archiver) SetObjectionLabel(label string) {
+	a.objection = label
+}
+
+// objectionLabel returns the configured objection label or the default
+func (a *Archiver) objectionLabel() string {
+	if a.objection == "" {
+		return DefaultObjectionLabel
+	}
+	return a.objection
+}
@@ -0,0 +1,55 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// Quarantine markers. A repository in quarantine carries the topic, and the
+// commit adding the notice file records when the quarantine started.
+const (
+	QuarantineTopic = "pending-archive"
+	NoticeFile      = "ARCHIVED.md"
+)
+
+// noticeText is the content of the notice file added to quarantined
+// repositories
+const noticeText = `# Scheduled for archiving
+
+This repository has had no activity since %s and is scheduled to be
+archived after %s.
+
+To keep it, remove the %q topic from the repository before then.
+`
+
+// Quarantine starts the quarantine period of a repository by committing a
+// notice file and tagging it with QuarantineTopic
+func (a *Archiver) Quarantine(ctx context.Context, repo github.Repository, period time.Duration) error {
+	logger.Info("Quarantining %s/%s for %v...", repo.Owner, repo.Name, period)
+
+	// Rewriting an earlier notice restarts the quarantine from this commit
+	notice := fmt.Sprintf(noticeText,
+		repo.LastActivity.Format("2006-01-02"), a.clock.Now().Add(period).Format("2006-01-02"), QuarantineTopic)
+	message := fmt.Sprintf("Add %s: scheduled for archiving", NoticeFile)
+	if err := a.client.PutFile(ctx, repo.Owner, repo.Name, NoticeFile, message, []byte(notice)); err != nil {
+		return err
+	}
+
+	if !repo.HasTopic(QuarantineTopic) {
+		if err := a.client.AddTopic(ctx, repo.Owner, repo.Name, repo.Topics, QuarantineTopic); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QuarantineStart returns when the quarantine of a repository started, the
+// date of the commit adding its notice file, or the zero time if it has no
+// notice
+func (a *Archiver) QuarantineStart(ctx context.Context, repo github.Repository) (time.Time, error) {
+	return a.client.GetLastCommitDate(ctx, repo.Owner, repo.Name, NoticeFile)
+}
@@ -0,0 +1,39 @@
+package archiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// ExportSettings saves the webhooks, branch protection, collaborators and
+// secret names of a repository as JSON in dir/<owner>/<name>.json and
+// returns the path of the file
+func (a *Archiver) ExportSettings(ctx context.Context, repo github.Repository, dir string) (string, error) {
+	settings, err := a.client.GetSettings(ctx, repo.Owner, repo.Name, repo.DefaultBranch)
+	if err != nil {
+		return "", err
+	}
+	if len(settings.Unavailable) > 0 {
+		logger.Warn("Could not read %v of %s/%s; exporting the rest", settings.Unavailable, repo.Owner, repo.Name)
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode settings: %w", err)
+	}
+	path := filepath.Join(dir, repo.Owner, repo.Name+".json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write settings: %w", err)
+	}
+	logger.Info("Exported settings of %s/%s to %s", repo.Owner, repo.Name, path)
+	return path, nil
+}
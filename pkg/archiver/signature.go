@@ -0,0 +1,48 @@
+package archiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// ErrSignatureMismatch is returned when the head commit of a fork does not
+// carry the signature verification of the original's
+var ErrSignatureMismatch = errors.New("fork does not preserve the signature verification of the original")
+
+// SetVerifySignatures makes the fork strategy compare the signature
+// verification of the default branch head of each fork with the original's
+// before deleting or archiving anything. A mismatch fails the repository,
+// keeping the original.
+func (a *Archiver) SetVerifySignatures(verify bool) {
+	a.verifySigs = verify
+}
+
+// verifySignatures compares the signature verification of the head commits
+// of the original repository and of its fork
+func (a *Archiver) verifySignatures(ctx context.Context, owner, repo, namespace, fork, branch string) error {
+	if branch == "" {
+		logger.Info("Repository %s/%s has no default branch, no signature to verify", owner, repo)
+		return nil
+	}
+	original, err := a.client.GetHeadVerification(ctx, owner, repo, branch)
+	if err != nil {
+		return err
+	}
+	copied, err := a.client.GetHeadVerification(ctx, namespace, fork, branch)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Signature of %s/%s@%s %.7s: signed %v, verified %v (%s)",
+		owner, repo, branch, original.SHA, original.Signed, original.Verified, original.Reason)
+	logger.Info("Signature of %s/%s@%s %.7s: signed %v, verified %v (%s)",
+		namespace, fork, branch, copied.SHA, copied.Signed, copied.Verified, copied.Reason)
+	if original.SHA != copied.SHA || original.Signed != copied.Signed || original.Verified != copied.Verified {
+		return fmt.Errorf("%s/%s: %w: %s on %.7s, %s on %.7s", owner, repo, ErrSignatureMismatch,
+			original.Reason, original.SHA, copied.Reason, copied.SHA)
+	}
+	return nil
+}
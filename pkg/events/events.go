@@ -0,0 +1,66 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types. These names are part of the stable event schema.
+const (
+	RepoListed   = "repo-listed"
+	RepoAnalyzed = "repo-analyzed"
+	RepoArchived = "repo-archived"
+	Error        = "error"
+)
+
+// Event is a single machine-readable record written as one JSON line.
+// Fields are only ever added to the schema, never renamed or removed.
+type Event struct {
+	Type    string                 `json:"type"`
+	Time    time.Time              `json:"time"`
+	Owner   string                 `json:"owner,omitempty"`
+	Repo    string                 `json:"repo,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// Stream writes events as newline-delimited JSON
+type Stream struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// New creates a new Stream writing to w
+func New(w io.Writer) *Stream {
+	return &Stream{enc: json.NewEncoder(w)}
+}
+
+// Emit writes an event, stamping it with the current time if unset
+func (s *Stream) Emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Events are best effort and must never interrupt the run
+	_ = s.enc.Encode(event)
+}
+
+// Default stream, disabled until an output is set
+var defaultStream *Stream
+
+// SetDefaultOutput enables the default stream, writing to w
+func SetDefaultOutput(w io.Writer) {
+	defaultStream = New(w)
+}
+
+// Emit writes an event to the default stream if one is configured
+func Emit(event Event) {
+	if defaultStream == nil {
+		return
+	}
+	defaultStream.Emit(event)
+}
@@ -0,0 +1,252 @@
+package filter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/github"
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// FetchTimeout bounds how long fetching a remote pattern list may take
+const FetchTimeout = 30 * time.Second
+
+// Filter decides which repositories are eligible for processing. A
+// repository is eligible if it matches at least one include regex (when any
+// are set) and matches no exclude glob or exclude regex; exclusions always
+// win.
+type Filter struct {
+	exclude      []string
+	ignore       []ignoreRule
+	includeRegex []*regexp.Regexp
+	excludeRegex []*regexp.Regexp
+}
+
+// ignoreRule is a single line of an ignore file
+type ignoreRule struct {
+	pattern string
+	negate  bool
+}
+
+// IgnoreFile is the name of the ignore file read from the working directory
+const IgnoreFile = ".archiverignore"
+
+// New creates an empty filter that allows every repository
+func New() *Filter {
+	return &Filter{}
+}
+
+// AddExcludes adds glob patterns for repositories that must never be
+// processed. Patterns containing a slash match owner/name, others match the
+// repository name alone.
+func (f *Filter) AddExcludes(patterns ...string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		f.exclude = append(f.exclude, pattern)
+	}
+	return nil
+}
+
+// AddIgnoreRules adds patterns with gitignore-style semantics: a pattern
+// excludes matching repositories, a pattern starting with '!' includes them
+// again, and the last matching pattern decides. A leading backslash escapes
+// a literal '!' or '#'. Negation only re-includes repositories excluded by
+// earlier ignore rules, never those excluded by other means.
+func (f *Filter) AddIgnoreRules(patterns ...string) error {
+	for _, pattern := range patterns {
+		rule := ignoreRule{pattern: pattern}
+		if strings.HasPrefix(pattern, "!") {
+			rule = ignoreRule{pattern: pattern[1:], negate: true}
+		} else if strings.HasPrefix(pattern, "\\") {
+			rule.pattern = pattern[1:]
+		}
+		if _, err := path.Match(rule.pattern, ""); err != nil || rule.pattern == "" {
+			return fmt.Errorf("invalid ignore pattern %q", pattern)
+		}
+		f.ignore = append(f.ignore, rule)
+	}
+	return nil
+}
+
+// ignored reports whether the ignore rules exclude a repository
+func (f *Filter) ignored(repo github.Repository) bool {
+	ignored := false
+	for _, rule := range f.ignore {
+		if matchPattern(rule.pattern, repo) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// AddIncludeRegex restricts processing to repositories whose owner/name
+// matches the regular expression, or any other include regex
+func (f *Filter) AddIncludeRegex(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid include regex %q: %w", expr, err)
+	}
+	f.includeRegex = append(f.includeRegex, re)
+	return nil
+}
+
+// AddExcludeRegex excludes repositories whose owner/name matches the regular
+// expression
+func (f *Filter) AddExcludeRegex(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid exclude regex %q: %w", expr, err)
+	}
+	f.excludeRegex = append(f.excludeRegex, re)
+	return nil
+}
+
+// Excluded reports whether a repository matches any exclude pattern or
+// fails to match the include regexes
+func (f *Filter) Excluded(repo github.Repository) bool {
+	for _, pattern := range f.exclude {
+		if matchPattern(pattern, repo) {
+			return true
+		}
+	}
+	if f.ignored(repo) {
+		return true
+	}
+
+	subject := repo.Owner + "/" + repo.Name
+	for _, re := range f.excludeRegex {
+		if re.MatchString(subject) {
+			return true
+		}
+	}
+	if len(f.includeRegex) == 0 {
+		return false
+	}
+	for _, re := range f.includeRegex {
+		if re.MatchString(subject) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the repositories that are not excluded
+func (f *Filter) Apply(repos []github.Repository) []github.Repository {
+	result := make([]github.Repository, 0, len(repos))
+	for _, repo := range repos {
+		if f.Excluded(repo) {
+			logger.Debug("Skipping %s/%s - excluded", repo.Owner, repo.Name)
+			continue
+		}
+		result = append(result, repo)
+	}
+	if skipped := len(repos) - len(result); skipped > 0 {
+		logger.Info("Excluded %d repositories", skipped)
+	}
+	return result
+}
+
+// matchPattern matches a glob against owner/name or the bare name
+func matchPattern(pattern string, repo github.Repository) bool {
+	subject := repo.Name
+	if strings.Contains(pattern, "/") {
+		subject = repo.Owner + "/" + repo.Name
+	}
+	matched, _ := path.Match(pattern, subject)
+	return matched
+}
+
+// Fetched pattern lists, cached for the lifetime of the process
+var (
+	cacheMu sync.Mutex
+	cache   = map[string][]string{}
+)
+
+// LoadPatterns reads patterns, one per line, from a local file or an
+// http(s) URL. Blank lines and lines starting with '#' are ignored.
+// Results are cached so each source is only read once per run.
+func LoadPatterns(ctx context.Context, source string) ([]string, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if patterns, ok := cache[source]; ok {
+		return patterns, nil
+	}
+
+	var r io.ReadCloser
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		r, err = fetch(ctx, source)
+	} else {
+		r, err = os.Open(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load patterns from %s: %w", source, err)
+	}
+	defer r.Close()
+
+	patterns, err := parsePatterns(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patterns from %s: %w", source, err)
+	}
+	logger.Debug("Loaded %d patterns from %s", len(patterns), source)
+	cache[source] = patterns
+	return patterns, nil
+}
+
+// fetch downloads a remote pattern list
+func fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// cancelReadCloser releases the request context when the body is closed
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context
+func (c *cancelReadCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// parsePatterns reads one pattern per line, skipping blanks and comments
+func parsePatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
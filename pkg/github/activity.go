@@ -0,0 +1,142 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/google/go-github/v59/github"
+)
+
+// Activity holds the timestamp of each activity signal of a repository. A
+// zero time means the signal shows no activity or is unavailable.
+type Activity struct {
+	Pushed      time.Time
+	Updated     time.Time
+	Created     time.Time
+	Issue       time.Time // most recently updated open issue or pull request
+	Release     time.Time // most recent release
+	WorkflowRun time.Time // most recent GitHub Actions run
+}
+
+// Latest returns the most recent timestamp across all signals and the name
+// of the signal it came from
+func (a Activity) Latest() (time.Time, string) {
+	var latest time.Time
+	var signal string
+	for _, s := range []struct {
+		name string
+		t    time.Time
+	}{
+		{string(SourcePushed), a.Pushed},
+		{string(SourceUpdated), a.Updated},
+		{string(SourceCreated), a.Created},
+		{"issues", a.Issue},
+		{"releases", a.Release},
+		{"workflow runs", a.WorkflowRun},
+	} {
+		if s.t.After(latest) {
+			latest, signal = s.t, s.name
+		}
+	}
+	return latest, signal
+}
+
+// ActivityQuery selects the signals GetLastActivity consults beyond the
+// listing's timestamp and open issues
+type ActivityQuery struct {
+	Source    ThresholdSource // listing timestamp used as the base
+	Cutoff    time.Time       // stop once activity is after this; zero checks every signal
+	Releases  bool            // consult the most recent release
+	Workflows bool            // consult the most recent GitHub Actions run
+}
+
+// GetLastActivity gathers the activity signals of a repository in one pass:
+// the base timestamp chosen by the query's source, then open issues and pull
+// requests, then releases and workflow runs if requested. Once the latest
+// activity is after the cutoff the repository is clearly active and the
+// remaining signals are skipped, leaving them zero. Repositories whose
+// issues, releases or Actions are disabled or restricted count as having
+// none; other failures, such as bad credentials, are returned.
+func (c *Client) GetLastActivity(ctx context.Context, repo Repository, query ActivityQuery) (Activity, error) {
+	logger.Debug("Fetching last activity for %s/%s", repo.Owner, repo.Name)
+
+	var activity Activity
+	switch query.Source {
+	case SourceUpdated:
+		activity.Updated = repo.UpdatedAt
+	case SourceCreated:
+		activity.Created = repo.CreatedAt
+	default:
+		activity.Pushed = repo.PushedAt
+	}
+
+	checks := []struct {
+		enabled bool
+		field   *time.Time
+		fetch   func(ctx context.Context, owner, repo string) (time.Time, error)
+	}{
+		{true, &activity.Issue, c.GetLatestIssueActivity},
+		{query.Releases, &activity.Release, c.GetLatestRelease},
+		{query.Workflows, &activity.WorkflowRun, c.GetLatestWorkflowRun},
+	}
+	for _, check := range checks {
+		if latest, signal := activity.Latest(); !query.Cutoff.IsZero() && latest.After(query.Cutoff) {
+			logger.Debug("Repository %s/%s active on %s (%s), skipping the remaining signals",
+				repo.Owner, repo.Name, latest.Format("2006-01-02"), signal)
+			break
+		}
+		if !check.enabled {
+			continue
+		}
+		t, err := check.fetch(ctx, repo.Owner, repo.Name)
+		if err != nil {
+			return activity, err
+		}
+		*check.field = t
+	}
+
+	latest, signal := activity.Latest()
+	logger.Debug("Final last activity date for %s/%s: %s (%s)", repo.Owner, repo.Name, latest.Format("2006-01-02"), signal)
+	return activity, nil
+}
+
+// ProbeActivity fetches every activity signal of a repository rather than
+// stopping at the first that shows activity, for tuning thresholds. It
+// costs three API requests per repository on top of the listing.
+func (c *Client) ProbeActivity(ctx context.Context, repo Repository) (Activity, error) {
+	logger.Debug("Probing activity signals of %s/%s", repo.Owner, repo.Name)
+
+	activity, err := c.GetLastActivity(ctx, repo, ActivityQuery{Source: SourcePushed, Releases: true, Workflows: true})
+	activity.Updated = repo.UpdatedAt
+	activity.Created = repo.CreatedAt
+	return activity, err
+}
+
+// GetLatestRelease returns when the most recent release was published, or
+// the zero time if there are none
+func (c *Client) GetLatestRelease(ctx context.Context, owner, repo string) (time.Time, error) {
+	logger.Debug("Checking for releases in %s/%s", owner, repo)
+
+	releases, _, err := c.client.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Debug("Releases unavailable for %s/%s, treating as none", owner, repo)
+			return time.Time{}, nil
+		}
+		logger.Error("Failed to list releases for %s/%s: %v", owner, repo, err)
+		return time.Time{}, fmt.Errorf("failed to list releases: %w", err)
+	}
+	if len(releases) == 0 {
+		return time.Time{}, nil
+	}
+
+	// Drafts have no publication date
+	published := releases[0].GetPublishedAt().Time
+	if published.IsZero() {
+		published = releases[0].GetCreatedAt().Time
+	}
+	logger.Debug("Most recent release in %s/%s: %s", owner, repo, published.Format("2006-01-02"))
+	return published, nil
+}
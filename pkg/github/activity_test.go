@@ -0,0 +1,172 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// activityServer answers the activity endpoints of acme/tool with one
+// timestamp each and records which signals were requested. A forbidden
+// signal answers 403 with message instead.
+type activityServer struct {
+	issue, release, run time.Time
+	forbidden, message  string
+
+	mu        sync.Mutex
+	requested []string
+}
+
+func (s *activityServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	signal := strings.TrimPrefix(r.URL.Path, "/repos/acme/tool/")
+	s.mu.Lock()
+	s.requested = append(s.requested, signal)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if signal == s.forbidden {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"message": s.message})
+		return
+	}
+	switch signal {
+	case "issues":
+		json.NewEncoder(w).Encode([]map[string]time.Time{{"updated_at": s.issue}})
+	case "releases":
+		json.NewEncoder(w).Encode([]map[string]time.Time{{"published_at": s.release}})
+	case "actions/runs":
+		json.NewEncoder(w).Encode(map[string]any{
+			"total_count":   1,
+			"workflow_runs": []map[string]time.Time{{"created_at": s.run}},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestGetLastActivityShortCircuits(t *testing.T) {
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := cutoff.AddDate(0, -6, 0)
+	after := cutoff.AddDate(0, 1, 0)
+	all := ActivityQuery{Source: SourcePushed, Cutoff: cutoff, Releases: true, Workflows: true}
+
+	tests := []struct {
+		name      string
+		pushed    time.Time
+		server    *activityServer
+		query     ActivityQuery
+		requested []string
+		latest    time.Time
+		signal    string
+	}{
+		{
+			name:   "pushed after the cutoff",
+			pushed: after,
+			server: &activityServer{},
+			query:  all,
+			latest: after,
+			signal: "pushed",
+		},
+		{
+			name:      "issue after the cutoff",
+			pushed:    before,
+			server:    &activityServer{issue: after},
+			query:     all,
+			requested: []string{"issues"},
+			latest:    after,
+			signal:    "issues",
+		},
+		{
+			name:      "release after the cutoff",
+			pushed:    before,
+			server:    &activityServer{issue: before, release: after},
+			query:     all,
+			requested: []string{"issues", "releases"},
+			latest:    after,
+			signal:    "releases",
+		},
+		{
+			name:      "inactive",
+			pushed:    before,
+			server:    &activityServer{issue: before, release: before, run: before.AddDate(0, 0, 1)},
+			query:     all,
+			requested: []string{"issues", "releases", "actions/runs"},
+			latest:    before.AddDate(0, 0, 1),
+			signal:    "workflow runs",
+		},
+		{
+			name:      "activity on the cutoff is not after it",
+			pushed:    cutoff,
+			server:    &activityServer{issue: before, release: before, run: before},
+			query:     all,
+			requested: []string{"issues", "releases", "actions/runs"},
+			latest:    cutoff,
+			signal:    "pushed",
+		},
+		{
+			name:      "signals not requested",
+			pushed:    before,
+			server:    &activityServer{issue: before},
+			query:     ActivityQuery{Source: SourcePushed, Cutoff: cutoff},
+			requested: []string{"issues"},
+			latest:    before,
+			signal:    "pushed",
+		},
+		{
+			name:      "no cutoff checks every signal",
+			pushed:    after,
+			server:    &activityServer{issue: after, release: after, run: after},
+			query:     ActivityQuery{Source: SourcePushed, Releases: true, Workflows: true},
+			requested: []string{"issues", "releases", "actions/runs"},
+			latest:    after,
+			signal:    "pushed",
+		},
+		{
+			name:      "actions disabled",
+			pushed:    before,
+			server:    &activityServer{issue: before, release: before, forbidden: "actions/runs", message: "Actions is disabled for this repository."},
+			query:     all,
+			requested: []string{"issues", "releases", "actions/runs"},
+			latest:    before,
+			signal:    "pushed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tt.server
+			c := newTestClient(t, server)
+
+			repo := Repository{Owner: "acme", Name: "tool", PushedAt: tt.pushed}
+			activity, err := c.GetLastActivity(context.Background(), repo, tt.query)
+			if err != nil {
+				t.Fatalf("GetLastActivity: %v", err)
+			}
+			if strings.Join(server.requested, ",") != strings.Join(tt.requested, ",") {
+				t.Errorf("requested %q, want %q", server.requested, tt.requested)
+			}
+			latest, signal := activity.Latest()
+			if !latest.Equal(tt.latest) || signal != tt.signal {
+				t.Errorf("Latest() = %s (%s), want %s (%s)", latest, signal, tt.latest, tt.signal)
+			}
+		})
+	}
+}
+
+func TestGetLastActivityPermissionDenied(t *testing.T) {
+	before := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := activityServer{issue: before, forbidden: "releases", message: "Resource not accessible by personal access token"}
+	c := newTestClient(t, &server)
+
+	repo := Repository{Owner: "acme", Name: "tool", PushedAt: before}
+	query := ActivityQuery{Source: SourcePushed, Cutoff: before.AddDate(1, 0, 0), Releases: true, Workflows: true}
+	if _, err := c.GetLastActivity(context.Background(), repo, query); err == nil {
+		t.Fatal("GetLastActivity succeeded, want the permission error")
+	}
+	if strings.Join(server.requested, ",") != "issues,releases" {
+		t.Errorf("requested %q after the failure, want issues,releases", server.requested)
+	}
+}
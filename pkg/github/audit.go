@@ -0,0 +1,137 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/util"
+	"github.com/google/go-github/v59/github"
+)
+
+// maxAuditBody bounds the request bodies copied into audit records; larger
+// ones, such as file contents, are left out
+const maxAuditBody = 4096
+
+// Audit record stages. Every state-changing request is recorded before it
+// is sent, then again with GitHub's answer under the same ID.
+const (
+	AuditSending  = "sending"
+	AuditAnswered = "answered"
+)
+
+// ErrAuditFailed is returned instead of sending a state-changing request
+// that could not be recorded in the audit log
+var ErrAuditFailed = util.NewError(util.InternalError, errors.New("audit log unavailable, refusing to send the change"))
+
+// AuditRecord describes one state-changing API request: who sent it, what
+// it asked for and how GitHub answered
+type AuditRecord struct {
+	ID     uint64          `json:"id"`
+	Stage  string          `json:"stage"`
+	Time   time.Time       `json:"time"`
+	User   string          `json:"user"`
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Status int             `json:"status,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Auditor keeps a record of every state-changing request
+type Auditor interface {
+	Audit(record AuditRecord) error
+}
+
+// SetAuditor sends a record of every state-changing request, that is every
+// request but GET and HEAD, to the auditor before the request is sent, and
+// another once GitHub has answered or the request has failed. A request that
+// cannot be recorded beforehand is not sent. Records name user as the one
+// responsible.
+func (c *Client) SetAuditor(user string, auditor Auditor) {
+	c.transport.mu.Lock()
+	defer c.transport.mu.Unlock()
+	c.transport.auditUser = user
+	c.transport.auditor = auditor
+}
+
+// auditBody returns a copy of a request body small enough to audit
+func auditBody(req *http.Request) json.RawMessage {
+	if req.GetBody == nil || req.ContentLength > maxAuditBody {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, maxAuditBody+1))
+	if err != nil || len(data) > maxAuditBody || !json.Valid(data) {
+		return nil
+	}
+	return bytes.TrimSpace(data)
+}
+
+// auditSending records a state-changing request before it is sent, if
+// auditing. It returns the record to complete with the answer, or an error
+// if the request must not be sent.
+func (t *transport) auditSending(req *http.Request) (*AuditRecord, error) {
+	if idempotent(req) {
+		return nil, nil
+	}
+	t.mu.Lock()
+	auditor := t.auditor
+	if auditor == nil {
+		t.mu.Unlock()
+		return nil, nil
+	}
+	t.auditSeq++
+	record := &AuditRecord{
+		ID:     t.auditSeq,
+		Stage:  AuditSending,
+		Time:   time.Now().UTC(),
+		User:   t.auditUser,
+		Method: req.Method,
+		Path:   req.URL.Path,
+	}
+	t.mu.Unlock()
+
+	record.Body = auditBody(req)
+	if err := auditor.Audit(*record); err != nil {
+		logger.Error("Not sending %s %s: failed to audit it: %v", req.Method, req.URL.Path, err)
+		return nil, fmt.Errorf("%s %s: %w: %v", req.Method, req.URL.Path, ErrAuditFailed, err)
+	}
+	return record, nil
+}
+
+// auditAnswered records how GitHub answered an audited request. The change
+// has been made by then, so a failure to record it is only logged.
+func (t *transport) auditAnswered(record *AuditRecord, resp *http.Response, err error) {
+	if record == nil {
+		return
+	}
+	t.mu.Lock()
+	auditor := t.auditor
+	t.mu.Unlock()
+
+	record.Stage = AuditAnswered
+	record.Time = time.Now().UTC()
+	record.Body = nil
+	switch {
+	case err != nil:
+		record.Error = err.Error()
+	case resp.StatusCode >= 400:
+		record.Status = resp.StatusCode
+		record.Error = github.CheckResponse(resp).Error()
+	default:
+		record.Status = resp.StatusCode
+	}
+	if err := auditor.Audit(*record); err != nil {
+		logger.Error("Failed to audit the answer to %s %s: %v", record.Method, record.Path, err)
+	}
+}
@@ -0,0 +1,70 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// recordingAuditor keeps audit records in memory, failing when told to
+type recordingAuditor struct {
+	mu      sync.Mutex
+	records []AuditRecord
+	err     error
+}
+
+func (a *recordingAuditor) Audit(record AuditRecord) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	a.records = append(a.records, record)
+	return nil
+}
+
+func TestAuditRecordsBeforeAndAfter(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	auditor := &recordingAuditor{}
+	c.SetAuditor("octo", auditor)
+
+	if err := c.DeleteRepository(context.Background(), "acme", "tool"); err != nil {
+		t.Fatalf("DeleteRepository() = %v", err)
+	}
+	if len(auditor.records) != 2 {
+		t.Fatalf("got %d records, want 2: %+v", len(auditor.records), auditor.records)
+	}
+	sending, answered := auditor.records[0], auditor.records[1]
+	if sending.Stage != AuditSending || sending.Method != http.MethodDelete || sending.Path != "/repos/acme/tool" || sending.User != "octo" {
+		t.Errorf("sending record = %+v", sending)
+	}
+	if answered.Stage != AuditAnswered || answered.ID != sending.ID || answered.Status != http.StatusNoContent {
+		t.Errorf("answered record = %+v", answered)
+	}
+}
+
+func TestAuditFailureBlocksChange(t *testing.T) {
+	sent := false
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sent = true
+		}
+		w.Write([]byte(`{"name":"tool","owner":{"login":"acme"}}`))
+	}))
+	c.SetAuditor("octo", &recordingAuditor{err: errors.New("disk full")})
+
+	err := c.DeleteRepository(context.Background(), "acme", "tool")
+	if !errors.Is(err, ErrAuditFailed) {
+		t.Errorf("DeleteRepository() = %v, want ErrAuditFailed", err)
+	}
+	if sent {
+		t.Error("change was sent although it could not be audited")
+	}
+	if _, err := c.GetRepository(context.Background(), "acme", "tool"); err != nil {
+		t.Errorf("GetRepository() = %v, want reads to be unaffected", err)
+	}
+}
@@ -2,42 +2,177 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/metrics"
 	"github.com/eyedeekay/github-archiver/pkg/util"
 	"github.com/google/go-github/v59/github"
 	"golang.org/x/oauth2"
 )
 
+// ErrNotFound is returned when a requested repository does not exist
+var ErrNotFound = errors.New("repository not found")
+
+// ErrForkDisabled is returned when a repository cannot be forked because
+// forking is disabled for it or by its organization's policy
+var ErrForkDisabled = errors.New("forking is disabled for this repository")
+
+// ErrPrivateRefused is returned when an archive cannot be made private,
+// such as the fork of a public repository, which keeps its parent's visibility
+var ErrPrivateRefused = errors.New("public repository cannot have a private archive")
+
+// ErrCollaboratorRefused is returned when GitHub refuses to add a
+// collaborator, such as an outside collaborator under a restrictive
+// organization policy
+var ErrCollaboratorRefused = errors.New("GitHub refused to add the collaborator")
+
+// ErrNameTaken is returned when the archive namespace already holds a
+// repository of the same name that is not a fork of the one being archived
+var ErrNameTaken = errors.New("archive namespace has an unrelated repository of the same name")
+
+// ErrNamespaceNotFound is returned when an archive namespace does not exist.
+// No repository can be archived into it, so it is a configuration error.
+var ErrNamespaceNotFound = util.NewError(util.UsageError, errors.New("archive namespace does not exist"))
+
 // Repository represents a GitHub repository with activity information
 type Repository struct {
-	Owner        string
-	Name         string
-	LastActivity time.Time
-	IsArchived   bool
+	Owner          string
+	Name           string
+	Description    string
+	LastActivity   time.Time
+	ActivitySignal string // what LastActivity was taken from, set by analysis
+	PushedAt       time.Time
+	UpdatedAt      time.Time
+	CreatedAt      time.Time
+	IsArchived     bool
+	Size           int // size in kilobytes
+	IsTemplate     bool
+	IsMirror       bool
+	IsFork         bool
+	Private        bool
+	Parent         string // full name of the upstream of a fork; only reported when fetching a single repository
+	Source         string // full name of the root of a fork's network; only reported when fetching a single repository
+	AllowForking   bool
+	Topics         []string
+	Stars          int
+	Watchers       int // subscribers; only reported when fetching a single repository
+	DefaultBranch  string
+	OpenIssues     int     // open issues and pull requests
+	Score          float64 // archivability, set by analysis when scoring
+	DeleteTier     bool    // past the deletion threshold, set by analysis when tiering
+	Admin          bool    // whether the token has admin rights, which deletion needs
+	HasPermissions bool    // whether the response reported the token's permissions
+	MovedFrom      string  // name it was requested under, if it moved; set when fetching a single repository
 }
 
 // Client wraps the GitHub API client
 type Client struct {
-	client *github.Client
+	client    *github.Client
+	transport *transport
+
+	namespaceType NamespaceType
+	nsMu          sync.Mutex
+	namespaces    map[string]error // existence check results by namespace
 }
 
-// NewClient creates a new GitHub client with the provided token
-func NewClient(ctx context.Context, token string) (*Client, error) {
+// DefaultUserAgent identifies the tool in GitHub's logs when no other User-Agent
+// is configured
+const DefaultUserAgent = "github-archiver"
+
+// NewClient creates a new GitHub client with the provided token. Requests
+// carry the given User-Agent, or DefaultUserAgent if it is empty.
+func NewClient(ctx context.Context, token, userAgent string) (*Client, error) {
 	logger.Debug("Creating new GitHub client")
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
+	return newClient(tc.Transport, userAgent), nil
+}
+
+// newClient creates a client sending requests through base
+func newClient(base http.RoundTripper, userAgent string) *Client {
+	t := &transport{
+		base:             base,
+		limiter:          newLimiter(DefaultMaxRPS),
+		retryBudget:      DefaultRetryBudget,
+		breakerThreshold: DefaultBreakerThreshold,
+	}
+
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	client := github.NewClient(&http.Client{Transport: t, CheckRedirect: checkRedirect})
+	client.UserAgent = userAgent
+	logger.Debug("Using User-Agent %q", userAgent)
+
 	return &Client{
-		client: github.NewClient(tc),
-	}, nil
+		client:    client,
+		transport: t,
+	}
+}
+
+// SetRetryPolicy changes the number of retries allowed across the whole run
+// and the number of consecutive transient failures that trips the circuit
+// breaker. A threshold of zero disables the breaker.
+func (c *Client) SetRetryPolicy(budget, breakerThreshold int) {
+	c.transport.mu.Lock()
+	defer c.transport.mu.Unlock()
+	c.transport.retryBudget = budget
+	c.transport.breakerThreshold = breakerThreshold
+}
+
+// SetIsRetryable replaces the classifier deciding which failed requests are
+// transient: retried within the budget and counted by the circuit breaker.
+// Error responses are passed as the errors the API client would return. Rate
+// limits never reach it, since the transport waits for them to reset. A nil
+// classifier restores DefaultIsRetryable.
+func (c *Client) SetIsRetryable(isRetryable func(error) bool) {
+	c.transport.mu.Lock()
+	defer c.transport.mu.Unlock()
+	c.transport.isRetryable = isRetryable
+}
+
+// SetMaxRPS bounds the rate of requests made by this client across every
+// phase of the run. A non-positive rate disables limiting. It is safe to
+// call while requests are in flight.
+func (c *Client) SetMaxRPS(rps float64) {
+	c.transport.limiter.SetRate(rps)
+}
+
+// SetMetrics counts every API request sent by this client in counters
+func (c *Client) SetMetrics(counters *metrics.Counters) {
+	c.transport.metrics = counters
+}
+
+// RateUsage returns the core API quota consumed by this client so far
+func (c *Client) RateUsage() RateUsage {
+	return c.transport.snapshot()
+}
+
+// ListFilters narrows which repositories ListRepositories returns. The zero
+// value lists every repository the target owns.
+type ListFilters struct {
+	// Affiliation lists the authenticated user's repositories by relationship
+	// instead of the target's: a comma-separated list of owner, collaborator
+	// and organization_member
+	Affiliation string
+	// Type is passed through as the listing endpoint's type filter, e.g.
+	// owner or member for users, or sources or forks for organizations
+	Type string
+	// AdminOnly keeps only repositories the token has admin rights on
+	AdminOnly bool
 }
 
 // ListRepositories fetches all repositories for a user or organization
-func (c *Client) ListRepositories(ctx context.Context, target string, org bool) ([]Repository, error) {
+func (c *Client) ListRepositories(ctx context.Context, target string, org bool, filters ListFilters) ([]Repository, error) {
 	var allRepos []*github.Repository
 	entityType := "user"
 	if org {
@@ -46,50 +181,241 @@ func (c *Client) ListRepositories(ctx context.Context, target string, org bool)
 
 	logger.Info("Fetching repositories for %s %s", entityType, target)
 
+	var err error
 	if !org {
-		opts := &github.RepositoryListOptions{
-			ListOptions: github.ListOptions{PerPage: 100},
+		// Affiliation is only supported when listing the authenticated user
+		user := target
+		if filters.Affiliation != "" {
+			logger.Debug("Listing authenticated user repositories with affiliation %s", filters.Affiliation)
+			user = ""
 		}
 
-		for {
-			logger.Debug("Fetching page %d of user repositories", opts.Page+1)
-			repos, resp, err := c.client.Repositories.List(ctx, target, opts)
-			if util.ForceProcessing(err) {
-				logger.Error("Failed to list repositories for user %s: %v", target, err)
-				return nil, fmt.Errorf("failed to list repositories: %w", err)
+		allRepos, err = paginate("user repositories", func(page github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			return c.client.Repositories.List(ctx, user, &github.RepositoryListOptions{
+				Affiliation: filters.Affiliation,
+				Type:        filters.Type,
+				ListOptions: page,
+			})
+		})
+	} else {
+		allRepos, err = paginate("organization repositories", func(page github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			return c.client.Repositories.ListByOrg(ctx, target, &github.RepositoryListByOrgOptions{
+				Type:        filters.Type,
+				ListOptions: page,
+			})
+		})
+	}
+	if util.ForceProcessing(err) {
+		logger.Error("Failed to list repositories for %s %s: %v", entityType, target, err)
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	result := convertRepositories(allRepos)
+	if filters.AdminOnly {
+		admin := result[:0]
+		for _, repo := range result {
+			if repo.Admin {
+				admin = append(admin, repo)
 			}
+		}
+		logger.Debug("Kept %d of %d repositories with admin rights", len(admin), len(result))
+		result = admin
+	}
+	logger.Info("Successfully retrieved %d valid repositories for %s", len(result), target)
+	return result, nil
+}
 
-			logger.Debug("Retrieved %d repositories on page %d", len(repos), opts.Page+1)
-			allRepos = append(allRepos, repos...)
+// IsOrganization reports whether a namespace is an organization rather
+// than a user
+func (c *Client) IsOrganization(ctx context.Context, namespace string) (bool, error) {
+	_, resp, err := c.client.Organizations.Get(ctx, namespace)
+	if err == nil {
+		return true, nil
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	logger.Error("Failed to look up %s: %v", namespace, err)
+	return false, fmt.Errorf("failed to look up %s: %w", namespace, err)
+}
 
-			if resp.NextPage == 0 {
-				break
-			}
-			opts.Page = resp.NextPage
+// AuthenticatedUser returns the login of the token's user
+func (c *Client) AuthenticatedUser(ctx context.Context) (string, error) {
+	user, _, err := c.client.Users.Get(ctx, "")
+	if err != nil {
+		logger.Error("Failed to get the authenticated user: %v", err)
+		return "", fmt.Errorf("failed to get the authenticated user: %w", err)
+	}
+	return user.GetLogin(), nil
+}
+
+// CreateTeamDiscussion posts a discussion to a team. A token without the
+// write:discussion scope, or without access to the team, is only warned
+// about, since the post is a courtesy rather than part of the run.
+func (c *Client) CreateTeamDiscussion(ctx context.Context, org, slug, title, body string) error {
+	logger.Debug("Posting discussion %q to team %s/%s", title, org, slug)
+
+	discussion, _, err := c.client.Teams.CreateDiscussionBySlug(ctx, org, slug, github.TeamDiscussion{
+		Title: github.String(title),
+		Body:  github.String(body),
+	})
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Warn("Cannot post a discussion to team %s/%s; the token needs the write:discussion scope and access to the team: %v", org, slug, err)
+			return nil
 		}
-	} else {
-		opts := &github.RepositoryListByOrgOptions{
-			ListOptions: github.ListOptions{PerPage: 100},
+		logger.Error("Failed to post a discussion to team %s/%s: %v", org, slug, err)
+		return fmt.Errorf("failed to post team discussion: %w", err)
+	}
+	logger.Info("Posted the run summary to team %s/%s: %s", org, slug, discussion.GetHTMLURL())
+	return nil
+}
+
+// ListTeamRepositories fetches all repositories a team within an organization
+// has access to. The token requires read access to the organization's teams.
+func (c *Client) ListTeamRepositories(ctx context.Context, org, slug string) ([]Repository, error) {
+	logger.Info("Fetching repositories for team %s/%s", org, slug)
+
+	allRepos, err := paginate("team repositories", func(page github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return c.client.Teams.ListTeamReposBySlug(ctx, org, slug, &page)
+	})
+	if util.ForceProcessing(err) {
+		logger.Error("Failed to list repositories for team %s/%s: %v", org, slug, err)
+		return nil, fmt.Errorf("failed to list team repositories: %w", err)
+	}
+
+	result := convertRepositories(allRepos)
+	logger.Info("Successfully retrieved %d valid repositories for team %s/%s", len(result), org, slug)
+	return result, nil
+}
+
+// SearchResultCap is the maximum number of results the search API returns for
+// a single query, regardless of pagination
+const SearchResultCap = 1000
+
+// SearchInactiveRepositories uses the search API to find repositories of a
+// user or organization that were last pushed before the cutoff. The returned
+// bool reports whether the results were capped or incomplete, in which case
+// searching stops at the first page and callers should fall back to
+// ListRepositories or narrow the query. Search requests have their own,
+// lower rate limit than the core API.
+func (c *Client) SearchInactiveRepositories(ctx context.Context, target string, org bool, cutoff time.Time) ([]Repository, bool, error) {
+	qualifier := "user"
+	if org {
+		qualifier = "org"
+	}
+	query := fmt.Sprintf("%s:%s pushed:<%s archived:false fork:true", qualifier, target, cutoff.Format("2006-01-02"))
+	logger.Info("Searching repositories with query %q", query)
+
+	capped := false
+	allRepos, err := paginate("search results", func(page github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		result, resp, err := c.client.Search.Repositories(ctx, query, &github.SearchOptions{ListOptions: page})
+		if err != nil {
+			return nil, resp, err
+		}
+		if result.GetTotal() > SearchResultCap {
+			logger.Warn("Search matched %d repositories, more than the %d result cap", result.GetTotal(), SearchResultCap)
+			capped = true
 		}
+		if result.GetIncompleteResults() {
+			logger.Warn("Search returned incomplete results for %s", target)
+			capped = true
+		}
+		// The remaining pages cannot complete the results, so a nil
+		// response ends pagination rather than spend the search quota
+		if capped {
+			return result.Repositories, nil, nil
+		}
+		return result.Repositories, resp, nil
+	})
+	if err != nil {
+		logger.Error("Failed to search repositories for %s: %v", target, err)
+		return nil, false, fmt.Errorf("failed to search repositories: %w", err)
+	}
 
-		for {
-			logger.Debug("Fetching page %d of organization repositories", opts.Page+1)
-			repos, resp, err := c.client.Repositories.ListByOrg(ctx, target, opts)
-			if util.ForceProcessing(err) {
-				logger.Error("Failed to list repositories for organization %s: %v", target, err)
-				return nil, fmt.Errorf("failed to list repositories: %w", err)
-			}
+	result := convertRepositories(allRepos)
+	logger.Info("Search found %d candidate repositories for %s", len(result), target)
+	return result, capped, nil
+}
 
-			logger.Debug("Retrieved %d repositories on page %d", len(repos), opts.Page+1)
-			allRepos = append(allRepos, repos...)
+// DedupeRepositories returns repos with duplicate owner/name pairs removed,
+// keeping the first occurrence of each.
+func DedupeRepositories(repos []Repository) []Repository {
+	seen := make(map[string]bool, len(repos))
+	result := make([]Repository, 0, len(repos))
+	for _, repo := range repos {
+		key := repo.Owner + "/" + repo.Name
+		if seen[key] {
+			logger.Debug("Skipping duplicate repository %s", key)
+			continue
+		}
+		seen[key] = true
+		result = append(result, repo)
+	}
+	return result
+}
 
-			if resp.NextPage == 0 {
-				break
-			}
-			opts.Page = resp.NextPage
+// ThresholdSource selects which repository timestamp is the base for
+// last activity, before other activity signals are layered on top
+type ThresholdSource string
+
+const (
+	// SourcePushed uses the time of the last push to any branch
+	SourcePushed ThresholdSource = "pushed"
+	// SourceUpdated uses the last update time, which metadata changes such
+	// as editing the description or topics also bump
+	SourceUpdated ThresholdSource = "updated"
+	// SourceCreated uses the creation time of the repository
+	SourceCreated ThresholdSource = "created"
+)
+
+// ParseThresholdSource converts a source name into a ThresholdSource
+func ParseThresholdSource(name string) (ThresholdSource, error) {
+	switch s := ThresholdSource(name); s {
+	case SourcePushed, SourceUpdated, SourceCreated:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown threshold source %q (valid: %s, %s, %s)", name, SourcePushed, SourceUpdated, SourceCreated)
+}
+
+// BaseActivity returns the repository timestamp selected by source
+func (r Repository) BaseActivity(source ThresholdSource) time.Time {
+	switch source {
+	case SourceUpdated:
+		return r.UpdatedAt
+	case SourceCreated:
+		return r.CreatedAt
+	default:
+		return r.PushedAt
+	}
+}
+
+// SortRepositories orders repositories by owner/name, case-insensitively,
+// so runs over the same account process repositories in a stable order
+func SortRepositories(repos []Repository) {
+	sort.SliceStable(repos, func(i, j int) bool {
+		return repos[i].SortKey() < repos[j].SortKey()
+	})
+}
+
+// SortKey returns the lowercase owner/name used to order repositories
+func (r Repository) SortKey() string {
+	return strings.ToLower(r.Owner + "/" + r.Name)
+}
+
+// HasTopic reports whether the repository is tagged with the topic
+func (r Repository) HasTopic(topic string) bool {
+	for _, t := range r.Topics {
+		if t == topic {
+			return true
 		}
 	}
+	return false
+}
 
+// convertRepositories converts API repositories into Repository values,
+// skipping any with incomplete data
+func convertRepositories(allRepos []*github.Repository) []Repository {
 	logger.Debug("Processing %d repositories", len(allRepos))
 	result := make([]Repository, 0, len(allRepos))
 	for _, repo := range allRepos {
@@ -102,31 +428,72 @@ func (c *Client) ListRepositories(ctx context.Context, target string, org bool)
 			Name:       *repo.Name,
 			IsArchived: repo.GetArchived(),
 			// We'll get the actual last activity in the analyzer
-			LastActivity: repo.GetUpdatedAt().Time,
+			LastActivity: repo.GetPushedAt().Time,
+			PushedAt:     repo.GetPushedAt().Time,
+			UpdatedAt:    repo.GetUpdatedAt().Time,
+			CreatedAt:    repo.GetCreatedAt().Time,
+			Size:         repo.GetSize(),
+			IsTemplate:   repo.GetIsTemplate(),
+			IsMirror:     repo.GetMirrorURL() != "",
+			IsFork:       repo.GetFork(),
+			Private:      repo.GetPrivate(),
+			Parent:       repo.GetParent().GetFullName(),
+			Source:       repo.GetSource().GetFullName(),
+			// Only reported for private repositories; others can always be forked
+			AllowForking:  repo.AllowForking == nil || repo.GetAllowForking(),
+			Topics:        repo.Topics,
+			Description:   repo.GetDescription(),
+			Stars:         repo.GetStargazersCount(),
+			Watchers:      repo.GetSubscribersCount(),
+			DefaultBranch: repo.GetDefaultBranch(),
+			OpenIssues:    repo.GetOpenIssuesCount(),
+			// Search results do not report permissions
+			Admin:          repo.GetPermissions()["admin"],
+			HasPermissions: repo.Permissions != nil,
 		})
 	}
-
-	logger.Info("Successfully retrieved %d valid repositories for %s", len(result), target)
-	return result, nil
+	return result
 }
 
-// GetLastActivity fetches the latest activity timestamp for a repository
-func (c *Client) GetLastActivity(ctx context.Context, owner, repo string) (time.Time, error) {
-	logger.Debug("Fetching last activity for %s/%s", owner, repo)
+// GetLatestIssueActivity returns the update time of the most recently
+// updated open issue or pull request, or the zero time if there are none.
+// Repositories whose issues are disabled or restricted are treated as having
+// none.
+func (c *Client) GetLatestIssueActivity(ctx context.Context, owner, repo string) (time.Time, error) {
+	logger.Debug("Checking for issue/PR activity in %s/%s", owner, repo)
 
-	// Get repository information
-	repository, _, err := c.client.Repositories.Get(ctx, owner, repo)
-	if util.ForceProcessing(err) {
-		logger.Error("Failed to get repository info for %s/%s: %v", owner, repo, err)
-		return time.Time{}, fmt.Errorf("failed to get repository info: %w", err)
+	issueOpts := &github.IssueListByRepoOptions{
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: 1,
+		},
 	}
 
-	// Start with the last push date
-	lastActivity := repository.GetPushedAt().Time
-	logger.Debug("Last push for %s/%s: %s", owner, repo, lastActivity.Format("2006-01-02"))
+	issues, _, err := c.client.Issues.ListByRepo(ctx, owner, repo, issueOpts)
+	switch {
+	case isUnavailable(err):
+		logger.Debug("Issues unavailable for %s/%s: %v", owner, repo, err)
+		return time.Time{}, nil
+	case err != nil:
+		logger.Error("Error checking issues/PRs for %s/%s: %v", owner, repo, err)
+		return time.Time{}, fmt.Errorf("failed to check issue activity: %w", err)
+	case len(issues) == 0:
+		return time.Time{}, nil
+	}
+	issueTime := issues[0].GetUpdatedAt().Time
+	logger.Debug("Most recent issue/PR activity: %s", issueTime.Format("2006-01-02"))
+	return issueTime, nil
+}
 
-	// Check for more recent issues/PRs
-	issueOpts := &github.IssueListByRepoOptions{
+// GetLatestOpenPullRequest returns the update time of the most recently
+// updated open pull request, or the zero time if there are none.
+// Repositories whose pull requests are disabled or restricted have none.
+func (c *Client) GetLatestOpenPullRequest(ctx context.Context, owner, repo string) (time.Time, error) {
+	logger.Debug("Checking for open pull requests in %s/%s", owner, repo)
+
+	opts := &github.PullRequestListOptions{
+		State:     "open",
 		Sort:      "updated",
 		Direction: "desc",
 		ListOptions: github.ListOptions{
@@ -134,57 +501,214 @@ func (c *Client) GetLastActivity(ctx context.Context, owner, repo string) (time.
 		},
 	}
 
-	logger.Debug("Checking for more recent issue/PR activity in %s/%s", owner, repo)
-	issues, _, err := c.client.Issues.ListByRepo(ctx, owner, repo, issueOpts)
-	if err == nil && len(issues) > 0 {
-		issueTime := issues[0].GetUpdatedAt()
-		logger.Debug("Most recent issue/PR activity: %s", issueTime.Format("2006-01-02"))
-		if issueTime.After(lastActivity) {
-			logger.Debug("Found more recent activity in issues/PRs: %s", issueTime.Format("2006-01-02"))
-			lastActivity = issueTime.Time
+	pulls, _, err := c.client.PullRequests.List(ctx, owner, repo, opts)
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Debug("Pull requests unavailable for %s/%s, treating as none", owner, repo)
+			return time.Time{}, nil
 		}
-	} else if util.ForceProcessing(err) {
-		logger.Warn("Error checking issues/PRs for %s/%s: %v", owner, repo, err)
+		logger.Error("Failed to list pull requests for %s/%s: %v", owner, repo, err)
+		return time.Time{}, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+	if len(pulls) == 0 {
+		logger.Debug("No open pull requests in %s/%s", owner, repo)
+		return time.Time{}, nil
 	}
 
-	logger.Debug("Final last activity date for %s/%s: %s", owner, repo, lastActivity.Format("2006-01-02"))
-	return lastActivity, nil
+	updated := pulls[0].GetUpdatedAt().Time
+	logger.Debug("Most recent open pull request in %s/%s: #%d updated %s", owner, repo, pulls[0].GetNumber(), updated.Format("2006-01-02"))
+	return updated, nil
 }
 
-// CreateArchiveNamespace checks if the archive organization/user exists
-func (c *Client) CreateArchiveNamespace(ctx context.Context, namespace string) error {
-	logger.Debug("Checking if archive namespace %s exists", namespace)
+// GetLatestWorkflowRun returns the creation time of the most recent GitHub
+// Actions workflow run, or the zero time if there are none. Repositories with
+// Actions disabled or inaccessible are treated as having no runs.
+func (c *Client) GetLatestWorkflowRun(ctx context.Context, owner, repo string) (time.Time, error) {
+	logger.Debug("Checking for workflow runs in %s/%s", owner, repo)
 
-	// Check if the namespace exists as an organization
-	logger.Debug("Checking if %s exists as an organization", namespace)
-	_, _, err := c.client.Organizations.Get(ctx, namespace)
-	if err == nil {
-		logger.Debug("Archive namespace %s exists as an organization", namespace)
-		return nil
+	opts := &github.ListWorkflowRunsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 1,
+		},
 	}
 
-	// Check if the namespace exists as a user
-	logger.Debug("Checking if %s exists as a user", namespace)
-	_, _, err = c.client.Users.Get(ctx, namespace)
-	if err == nil {
-		logger.Debug("Archive namespace %s exists as a user", namespace)
-		return nil
+	runs, _, err := c.client.Actions.ListRepositoryWorkflowRuns(ctx, owner, repo, opts)
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Debug("Actions unavailable for %s/%s, treating as no workflow runs", owner, repo)
+			return time.Time{}, nil
+		}
+		logger.Error("Failed to list workflow runs for %s/%s: %v", owner, repo, err)
+		return time.Time{}, fmt.Errorf("failed to list workflow runs: %w", err)
+	}
+	if runs == nil || len(runs.WorkflowRuns) == 0 {
+		logger.Debug("No workflow runs in %s/%s", owner, repo)
+		return time.Time{}, nil
+	}
+
+	created := runs.WorkflowRuns[0].GetCreatedAt().Time
+	logger.Debug("Most recent workflow run in %s/%s: %s", owner, repo, created.Format("2006-01-02"))
+	return created, nil
+}
+
+// GetWatchers returns the number of users watching a repository. Listings
+// do not include it, so it costs one request per repository.
+func (c *Client) GetWatchers(ctx context.Context, owner, repo string) (int, error) {
+	repository, err := c.GetRepository(ctx, owner, repo)
+	if err != nil {
+		return 0, err
+	}
+	return repository.Watchers, nil
+}
+
+// GetBranchProtection reports whether a branch is governed by branch
+// protection or a repository ruleset, and which. Branches without either,
+// and repositories where the settings cannot be read, are unprotected.
+func (c *Client) GetBranchProtection(ctx context.Context, owner, repo, branch string) (bool, string, error) {
+	logger.Debug("Checking protection of %s in %s/%s", branch, owner, repo)
+
+	_, _, err := c.client.Repositories.GetBranchProtection(ctx, owner, repo, branch)
+	switch {
+	case err == nil:
+		return true, "branch protection", nil
+	case !isUnavailable(err):
+		logger.Error("Failed to get branch protection for %s/%s: %v", owner, repo, err)
+		return false, "", fmt.Errorf("failed to get branch protection: %w", err)
+	}
+
+	rules, _, err := c.client.Repositories.GetRulesForBranch(ctx, owner, repo, branch)
+	switch {
+	case err == nil && len(rules) > 0:
+		return true, "ruleset", nil
+	case err != nil && !isUnavailable(err):
+		logger.Error("Failed to get rulesets for %s/%s: %v", owner, repo, err)
+		return false, "", fmt.Errorf("failed to get rulesets: %w", err)
 	}
+	return false, "", nil
+}
+
+// ProtectBranch applies branch protection that blocks force pushes and
+// deletion of a branch, for administrators too. It returns false without an
+// error if protection is unavailable, e.g. for private repositories on
+// plans without it.
+func (c *Client) ProtectBranch(ctx context.Context, owner, repo, branch string) (bool, error) {
+	logger.Debug("Protecting %s in %s/%s", branch, owner, repo)
 
-	logger.Error("Archive namespace %s does not exist", namespace)
-	// The GitHub API doesn't support programmatic creation of organizations
-	return fmt.Errorf("archive namespace '%s' does not exist and cannot be created automatically. Please create the organization or user account manually", namespace)
+	_, _, err := c.client.Repositories.UpdateBranchProtection(ctx, owner, repo, branch, &github.ProtectionRequest{
+		EnforceAdmins:    true,
+		AllowForcePushes: github.Bool(false),
+		AllowDeletions:   github.Bool(false),
+	})
+	switch {
+	case err == nil:
+		return true, nil
+	case isUnavailable(err):
+		logger.Debug("Branch protection unavailable for %s/%s: %v", owner, repo, err)
+		return false, nil
+	}
+	logger.Error("Failed to protect %s in %s/%s: %v", branch, owner, repo, err)
+	return false, fmt.Errorf("failed to protect branch: %w", err)
 }
 
-// ForkRepository forks a repository to the archive namespace
+// ListLanguages returns the languages GitHub detects in a repository, with
+// the number of bytes of each
+func (c *Client) ListLanguages(ctx context.Context, owner, repo string) (map[string]int, error) {
+	logger.Debug("Fetching languages of %s/%s", owner, repo)
+
+	languages, _, err := c.client.Repositories.ListLanguages(ctx, owner, repo)
+	if err != nil {
+		logger.Error("Failed to list languages of %s/%s: %v", owner, repo, err)
+		return nil, fmt.Errorf("failed to list languages: %w", err)
+	}
+	return languages, nil
+}
+
+// ListTopContributors returns the logins of up to 100 of a repository's
+// contributors with the most commits, excluding bots. Empty repositories and
+// ones too large for GitHub to list have none.
+func (c *Client) ListTopContributors(ctx context.Context, owner, repo string) ([]string, error) {
+	logger.Debug("Listing contributors of %s/%s", owner, repo)
+
+	contributors, _, err := c.client.Repositories.ListContributors(ctx, owner, repo, &github.ListContributorsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Debug("Contributors unavailable for %s/%s, treating as none", owner, repo)
+			return nil, nil
+		}
+		logger.Error("Failed to list contributors of %s/%s: %v", owner, repo, err)
+		return nil, fmt.Errorf("failed to list contributors: %w", err)
+	}
+
+	logins := make([]string, 0, len(contributors))
+	for _, contributor := range contributors {
+		login := contributor.GetLogin()
+		if login == "" || contributor.GetType() == "Bot" || strings.HasSuffix(login, "[bot]") {
+			continue
+		}
+		logins = append(logins, login)
+	}
+	return logins, nil
+}
+
+// IsOrgMember reports whether a user is a member of an organization.
+// Concealed memberships are only visible to members of the organization.
+func (c *Client) IsOrgMember(ctx context.Context, org, user string) (bool, error) {
+	member, _, err := c.client.Organizations.IsMember(ctx, org, user)
+	if err != nil {
+		logger.Error("Failed to check membership of %s in %s: %v", user, org, err)
+		return false, fmt.Errorf("failed to check organization membership: %w", err)
+	}
+	return member, nil
+}
+
+// CountCommits returns the number of commits on the default branch. It
+// requests a single commit per page and reads the total from the last page
+// of the Link header, so it costs one request per repository. Empty
+// repositories have no commits.
+func (c *Client) CountCommits(ctx context.Context, owner, repo string) (int, error) {
+	logger.Debug("Counting commits in %s/%s", owner, repo)
+
+	opts := &github.CommitsListOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 1,
+		},
+	}
+
+	commits, resp, err := c.client.Repositories.ListCommits(ctx, owner, repo, opts)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			logger.Debug("Repository %s/%s is empty", owner, repo)
+			return 0, nil
+		}
+		logger.Error("Failed to list commits for %s/%s: %v", owner, repo, err)
+		return 0, fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	count := len(commits)
+	if resp.LastPage > 0 {
+		count = resp.LastPage
+	}
+	logger.Debug("Repository %s/%s has %d commits", owner, repo, count)
+	return count, nil
+}
+
+// ForkRepository forks a repository to the archive namespace. A fork left
+// by an earlier run is reused; any other repository of the same name fails
+// with ErrNameTaken, since later steps would change or archive it.
 func (c *Client) ForkRepository(ctx context.Context, owner, repo, targetOrg string) error {
 	logger.Debug("Checking if %s/%s already exists", targetOrg, repo)
 
 	// Check if repository already exists in target org
-	_, _, err := c.client.Repositories.Get(ctx, targetOrg, repo)
+	existing, _, err := c.client.Repositories.Get(ctx, targetOrg, repo)
 	if err == nil {
-		// Repository already exists in target org
-		logger.Info("Repository %s/%s already exists, skipping fork creation", targetOrg, repo)
+		parent := existing.GetParent().GetFullName()
+		if !existing.GetFork() || !strings.EqualFold(parent, owner+"/"+repo) {
+			logger.Error("Repository %s/%s already exists and is not a fork of %s/%s", targetOrg, repo, owner, repo)
+			return fmt.Errorf("%s/%s: %w", targetOrg, repo, ErrNameTaken)
+		}
+		logger.Info("Fork %s/%s already exists, skipping fork creation", targetOrg, repo)
 		return nil
 	}
 
@@ -194,24 +718,81 @@ func (c *Client) ForkRepository(ctx context.Context, owner, repo, targetOrg stri
 		Organization: targetOrg,
 	}
 
+	// A failed fork is never downgraded: later steps would act on a copy
+	// that does not exist
 	_, _, err = c.client.Repositories.CreateFork(ctx, owner, repo, forkOpts)
-	if util.ForceProcessing(err) {
+	if errors.As(err, new(*github.AcceptedError)) {
+		// GitHub answers 202 Accepted and creates the fork in the background
+		err = nil
+	}
+	if isForkDisabled(err) {
+		logger.Warn("Forking is disabled for %s/%s: %v", owner, repo, err)
+		return fmt.Errorf("%s/%s: %w", owner, repo, ErrForkDisabled)
+	}
+	if err != nil {
 		logger.Error("Failed to fork %s/%s to %s: %v", owner, repo, targetOrg, err)
-		if err != nil {
-			return fmt.Errorf("failed to fork repository: %w", err)
-		}
+		return fmt.Errorf("failed to fork repository: %w", err)
 	}
 
 	logger.Debug("Successfully forked %s/%s to %s", owner, repo, targetOrg)
 	return nil
 }
 
+// GetRepository fetches the current state of a single repository. A renamed
+// or transferred repository is returned under its new name, with MovedFrom
+// set to the requested one.
+func (c *Client) GetRepository(ctx context.Context, owner, repo string) (Repository, error) {
+	logger.Debug("Fetching repository %s/%s", owner, repo)
+
+	repository, resp, err := c.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return Repository{}, fmt.Errorf("%s/%s: %w", owner, repo, ErrNotFound)
+		}
+		logger.Error("Failed to get repository info for %s/%s: %v", owner, repo, err)
+		return Repository{}, fmt.Errorf("failed to get repository info: %w", err)
+	}
+
+	repos := convertRepositories([]*github.Repository{repository})
+	if len(repos) == 0 {
+		return Repository{}, fmt.Errorf("repository %s/%s has incomplete data", owner, repo)
+	}
+	markMoved(owner, repo, &repos[0])
+	return repos[0], nil
+}
+
+// RepositoryExists reports whether a repository can be fetched
+func (c *Client) RepositoryExists(ctx context.Context, owner, repo string) (bool, error) {
+	_, resp, err := c.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get repository info: %w", err)
+	}
+	return true, nil
+}
+
+// RenameRepository changes the name of a repository
+func (c *Client) RenameRepository(ctx context.Context, owner, repo, newName string) error {
+	logger.Debug("Renaming repository %s/%s to %s", owner, repo, newName)
+
+	_, _, err := c.client.Repositories.Edit(ctx, owner, repo, &github.Repository{Name: github.String(newName)})
+	if err != nil {
+		logger.Error("Failed to rename repository %s/%s to %s: %v", owner, repo, newName, err)
+		return fmt.Errorf("failed to rename repository: %w", err)
+	}
+
+	logger.Debug("Successfully renamed repository %s/%s to %s", owner, repo, newName)
+	return nil
+}
+
 // DeleteRepository deletes a repository
 func (c *Client) DeleteRepository(ctx context.Context, owner, repo string) error {
 	logger.Debug("Deleting repository %s/%s", owner, repo)
 
 	_, err := c.client.Repositories.Delete(ctx, owner, repo)
-	if util.ForceProcessing(err) {
+	if err != nil {
 		logger.Error("Failed to delete repository %s/%s: %v", owner, repo, err)
 		return fmt.Errorf("failed to delete repository: %w", err)
 	}
@@ -220,7 +801,8 @@ func (c *Client) DeleteRepository(ctx context.Context, owner, repo string) error
 	return nil
 }
 
-// SetArchiveStatus marks a repository as archived
+// SetArchiveStatus marks a repository as archived, or unarchived. Nothing
+// is changed if the repository is already in the requested state.
 func (c *Client) SetArchiveStatus(ctx context.Context, owner, repo string, archived bool) error {
 	action := "archive"
 	if !archived {
@@ -230,7 +812,7 @@ func (c *Client) SetArchiveStatus(ctx context.Context, owner, repo string, archi
 	logger.Debug("%s repository %s/%s", action, owner, repo)
 
 	repository, _, err := c.client.Repositories.Get(ctx, owner, repo)
-	if util.ForceProcessing(err) {
+	if err != nil {
 		logger.Error("Failed to get repository info for %s/%s: %v", owner, repo, err)
 		return fmt.Errorf("failed to get repository info: %w", err)
 	}
@@ -240,10 +822,16 @@ func (c *Client) SetArchiveStatus(ctx context.Context, owner, repo string, archi
 		return fmt.Errorf("repository object is nil")
 	}
 
+	// Re-runs often find the repository already in the desired state
+	if repository.GetArchived() == archived {
+		logger.Debug("Repository %s/%s is already in the desired state (archived: %v), skipping %s", owner, repo, archived, action)
+		return nil
+	}
+
 	repository.Archived = github.Bool(archived)
 
 	_, _, err = c.client.Repositories.Edit(ctx, owner, repo, repository)
-	if util.ForceProcessing(err) {
+	if err != nil {
 		logger.Error("Failed to %s repository %s/%s: %v", action, owner, repo, err)
 		return fmt.Errorf("failed to update archive status: %w", err)
 	}
@@ -251,3 +839,258 @@ func (c *Client) SetArchiveStatus(ctx context.Context, owner, repo string, archi
 	logger.Debug("Successfully %sd repository %s/%s", action, owner, repo)
 	return nil
 }
+
+// AddTopic tags a repository with a topic, keeping its existing topics
+func (c *Client) AddTopic(ctx context.Context, owner, repo string, topics []string, topic string) error {
+	logger.Debug("Adding topic %s to %s/%s", topic, owner, repo)
+
+	_, _, err := c.client.Repositories.ReplaceAllTopics(ctx, owner, repo, append(append([]string{}, topics...), topic))
+	if err != nil {
+		logger.Error("Failed to add topic %s to %s/%s: %v", topic, owner, repo, err)
+		return fmt.Errorf("failed to add topic: %w", err)
+	}
+	return nil
+}
+
+// PutFile commits a file to the default branch of a repository, creating it
+// or replacing its contents if it already exists
+func (c *Client) PutFile(ctx context.Context, owner, repo, path, message string, content []byte) error {
+	logger.Debug("Writing %s in %s/%s", path, owner, repo)
+
+	opts := &github.RepositoryContentFileOptions{
+		Message: github.String(message),
+		Content: content,
+	}
+
+	existing, _, resp, err := c.client.Repositories.GetContents(ctx, owner, repo, path, nil)
+	switch {
+	case err == nil && existing != nil:
+		opts.SHA = existing.SHA
+		_, _, err = c.client.Repositories.UpdateFile(ctx, owner, repo, path, opts)
+	case err == nil, resp != nil && resp.StatusCode == http.StatusNotFound:
+		_, _, err = c.client.Repositories.CreateFile(ctx, owner, repo, path, opts)
+	}
+	if err != nil {
+		logger.Error("Failed to write %s in %s/%s: %v", path, owner, repo, err)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// GetLastCommitDate returns the date of the most recent commit touching
+// path on the default branch, or the zero time if there is none
+func (c *Client) GetLastCommitDate(ctx context.Context, owner, repo, path string) (time.Time, error) {
+	logger.Debug("Checking latest commit to %s in %s/%s", path, owner, repo)
+
+	opts := &github.CommitsListOptions{
+		Path: path,
+		ListOptions: github.ListOptions{
+			PerPage: 1,
+		},
+	}
+	commits, resp, err := c.client.Repositories.ListCommits(ctx, owner, repo, opts)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			return time.Time{}, nil
+		}
+		logger.Error("Failed to list commits to %s in %s/%s: %v", path, owner, repo, err)
+		return time.Time{}, fmt.Errorf("failed to list commits: %w", err)
+	}
+	if len(commits) == 0 {
+		return time.Time{}, nil
+	}
+	return commits[0].GetCommit().GetCommitter().GetDate().Time, nil
+}
+
+// Issue summarizes an issue or pull request
+type Issue struct {
+	Number        int
+	Title         string
+	Author        string
+	URL           string
+	IsPullRequest bool
+	State         string
+	Labels        []string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Comments      int
+}
+
+// HasLabel reports whether the issue carries a label, ignoring case
+func (i Issue) HasLabel(label string) bool {
+	for _, l := range i.Labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// newIssue converts an API issue
+func newIssue(issue *github.Issue) Issue {
+	labels := make([]string, 0, len(issue.Labels))
+	for _, label := range issue.Labels {
+		labels = append(labels, label.GetName())
+	}
+	return Issue{
+		Number:        issue.GetNumber(),
+		Title:         issue.GetTitle(),
+		Author:        issue.GetUser().GetLogin(),
+		URL:           issue.GetHTMLURL(),
+		IsPullRequest: issue.IsPullRequest(),
+		State:         issue.GetState(),
+		Labels:        labels,
+		CreatedAt:     issue.GetCreatedAt().Time,
+		UpdatedAt:     issue.GetUpdatedAt().Time,
+		Comments:      issue.GetComments(),
+	}
+}
+
+// ListOpenIssues fetches the open issues and pull requests of a repository.
+// Repositories with issues disabled or restricted have none.
+func (c *Client) ListOpenIssues(ctx context.Context, owner, repo string) ([]Issue, error) {
+	logger.Debug("Listing open issues of %s/%s", owner, repo)
+
+	issues, err := paginate("open issues", func(page github.ListOptions) ([]*github.Issue, *github.Response, error) {
+		return c.client.Issues.ListByRepo(ctx, owner, repo, &github.IssueListByRepoOptions{
+			State:       "open",
+			ListOptions: page,
+		})
+	})
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Debug("Issues unavailable for %s/%s, treating as none", owner, repo)
+			return nil, nil
+		}
+		logger.Error("Failed to list issues for %s/%s: %v", owner, repo, err)
+		return nil, fmt.Errorf("failed to list issues: %w", err)
+	}
+
+	result := make([]Issue, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, newIssue(issue))
+	}
+	return result, nil
+}
+
+// FindIssue returns the most recent issue, open or closed, opened by creator
+// with exactly this title. The bool is false if there is none or the
+// repository has issues disabled.
+func (c *Client) FindIssue(ctx context.Context, owner, repo, creator, title string) (Issue, bool, error) {
+	logger.Debug("Looking for issue %q in %s/%s", title, owner, repo)
+
+	issues, err := paginate("issues", func(page github.ListOptions) ([]*github.Issue, *github.Response, error) {
+		return c.client.Issues.ListByRepo(ctx, owner, repo, &github.IssueListByRepoOptions{
+			State:       "all",
+			Creator:     creator,
+			Sort:        "created",
+			Direction:   "desc",
+			ListOptions: page,
+		})
+	})
+	if err != nil {
+		if isUnavailable(err) {
+			return Issue{}, false, nil
+		}
+		logger.Error("Failed to list issues for %s/%s: %v", owner, repo, err)
+		return Issue{}, false, fmt.Errorf("failed to list issues: %w", err)
+	}
+	for _, issue := range issues {
+		if !issue.IsPullRequest() && issue.GetTitle() == title {
+			return newIssue(issue), true, nil
+		}
+	}
+	return Issue{}, false, nil
+}
+
+// CreateIssue opens an issue on a repository
+func (c *Client) CreateIssue(ctx context.Context, owner, repo, title, body string) (Issue, error) {
+	logger.Debug("Creating issue %q in %s/%s", title, owner, repo)
+
+	issue, _, err := c.client.Issues.Create(ctx, owner, repo, &github.IssueRequest{
+		Title: github.String(title),
+		Body:  github.String(body),
+	})
+	if err != nil {
+		logger.Error("Failed to create issue in %s/%s: %v", owner, repo, err)
+		return Issue{}, fmt.Errorf("failed to create issue: %w", err)
+	}
+	return newIssue(issue), nil
+}
+
+// ListAdmins returns the logins of users with admin access to a repository.
+// It returns none if the token cannot list collaborators.
+func (c *Client) ListAdmins(ctx context.Context, owner, repo string) ([]string, error) {
+	logger.Debug("Listing admins of %s/%s", owner, repo)
+	return c.listCollaborators(ctx, owner, repo, &github.ListCollaboratorsOptions{Permission: "admin"})
+}
+
+// ListDirectCollaborators returns the logins of users given access to a
+// repository directly, outside collaborators included, rather than through
+// a team or organization membership. It returns none if the token cannot
+// list collaborators.
+func (c *Client) ListDirectCollaborators(ctx context.Context, owner, repo string) ([]string, error) {
+	logger.Debug("Listing direct collaborators of %s/%s", owner, repo)
+	return c.listCollaborators(ctx, owner, repo, &github.ListCollaboratorsOptions{Affiliation: "direct"})
+}
+
+// listCollaborators returns the logins of the collaborators matching opts
+func (c *Client) listCollaborators(ctx context.Context, owner, repo string, opts *github.ListCollaboratorsOptions) ([]string, error) {
+	users, err := paginate("collaborators", func(page github.ListOptions) ([]*github.User, *github.Response, error) {
+		opts.ListOptions = page
+		return c.client.Repositories.ListCollaborators(ctx, owner, repo, opts)
+	})
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Debug("Cannot list collaborators of %s/%s: %v", owner, repo, err)
+			return nil, nil
+		}
+		logger.Error("Failed to list collaborators of %s/%s: %v", owner, repo, err)
+		return nil, fmt.Errorf("failed to list collaborators: %w", err)
+	}
+	logins := make([]string, 0, len(users))
+	for _, user := range users {
+		logins = append(logins, user.GetLogin())
+	}
+	return logins, nil
+}
+
+// AddReader gives a user read access to a repository. Users outside the
+// owning organization are invited rather than added, which is reported. It
+// returns ErrCollaboratorRefused if the owner's policy or plan does not
+// allow adding the user.
+func (c *Client) AddReader(ctx context.Context, owner, repo, login string) (bool, error) {
+	logger.Debug("Giving %s read access to %s/%s", login, owner, repo)
+
+	invitation, _, err := c.client.Repositories.AddCollaborator(ctx, owner, repo, login, &github.RepositoryAddCollaboratorOptions{Permission: "pull"})
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil &&
+		(respErr.Response.StatusCode == http.StatusUnprocessableEntity ||
+			(respErr.Response.StatusCode == http.StatusForbidden && respErr.Response.Header.Get("X-GitHub-SSO") == "")) {
+		return false, fmt.Errorf("%s on %s/%s: %w: %s", login, owner, repo, ErrCollaboratorRefused, respErr.Message)
+	}
+	if err != nil {
+		logger.Error("Failed to add %s to %s/%s: %v", login, owner, repo, err)
+		return false, fmt.Errorf("failed to add collaborator: %w", err)
+	}
+	return invitation != nil, nil
+}
+
+// CreateGist creates a secret gist holding a single file and returns its URL
+func (c *Client) CreateGist(ctx context.Context, description, filename, content string) (string, error) {
+	logger.Debug("Creating gist %s", filename)
+
+	gist := &github.Gist{
+		Description: github.String(description),
+		Public:      github.Bool(false),
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(filename): {Content: github.String(content)},
+		},
+	}
+	created, _, err := c.client.Gists.Create(ctx, gist)
+	if err != nil {
+		logger.Error("Failed to create gist %s: %v", filename, err)
+		return "", fmt.Errorf("failed to create gist: %w", err)
+	}
+	return created.GetHTMLURL(), nil
+}
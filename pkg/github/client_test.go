@@ -0,0 +1,232 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+func TestForkRepositoryAccepted(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme-archive/tool":
+			http.NotFound(w, r)
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/tool/forks":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"name":"tool","full_name":"acme-archive/tool","owner":{"login":"acme-archive"}}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	if err := c.ForkRepository(context.Background(), "acme", "tool", "acme-archive"); err != nil {
+		t.Fatalf("ForkRepository() = %v, want nil for 202 Accepted", err)
+	}
+}
+
+func TestForkRepositoryDisabled(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Repository forking is disabled"}`))
+	}))
+	err := c.ForkRepository(context.Background(), "acme", "tool", "acme-archive")
+	if !errors.Is(err, ErrForkDisabled) {
+		t.Fatalf("ForkRepository() = %v, want ErrForkDisabled", err)
+	}
+}
+
+// archiveServer serves a repository with the given archived state, counting
+// the edits it receives
+func archiveServer(t *testing.T, archived bool) (*Client, *int) {
+	t.Helper()
+	edits := 0
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/tool" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPatch {
+			edits++
+		}
+		fmt.Fprintf(w, `{"name":"tool","owner":{"login":"acme"},"archived":%v}`, archived)
+	}))
+	return c, &edits
+}
+
+func TestSetArchiveStatusAlreadyArchived(t *testing.T) {
+	c, edits := archiveServer(t, true)
+	if err := c.SetArchiveStatus(context.Background(), "acme", "tool", true); err != nil {
+		t.Fatalf("SetArchiveStatus() = %v", err)
+	}
+	if *edits != 0 {
+		t.Errorf("%d edits sent for an archived repository, want none", *edits)
+	}
+}
+
+func TestSetArchiveStatusArchives(t *testing.T) {
+	c, edits := archiveServer(t, false)
+	if err := c.SetArchiveStatus(context.Background(), "acme", "tool", true); err != nil {
+		t.Fatalf("SetArchiveStatus() = %v", err)
+	}
+	if *edits != 1 {
+		t.Errorf("%d edits sent, want 1", *edits)
+	}
+}
+
+// searchServer answers a search with one repository on each of three pages,
+// reporting total matches and counting the pages requested
+func searchServer(t *testing.T, total int, incomplete bool) (*Client, *int) {
+	t.Helper()
+	pages := 0
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/repositories" {
+			http.NotFound(w, r)
+			return
+		}
+		pages++
+		if pages < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next"`, r.Host, r.URL.Path, pages+1))
+		}
+		fmt.Fprintf(w, `{"total_count":%d,"incomplete_results":%v,"items":[{"name":"tool-%d","owner":{"login":"acme"}}]}`,
+			total, incomplete, pages)
+	}))
+	return c, &pages
+}
+
+func TestSearchInactiveRepositoriesPaginates(t *testing.T) {
+	c, pages := searchServer(t, 3, false)
+	repos, capped, err := c.SearchInactiveRepositories(context.Background(), "acme", true, time.Now())
+	if err != nil {
+		t.Fatalf("SearchInactiveRepositories: %v", err)
+	}
+	if capped || len(repos) != 3 || *pages != 3 {
+		t.Errorf("got %d repositories from %d pages, capped %v; want 3 from 3, not capped", len(repos), *pages, capped)
+	}
+}
+
+func TestSearchInactiveRepositoriesStopsWhenCapped(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		total      int
+		incomplete bool
+	}{
+		{"over the result cap", SearchResultCap + 1, false},
+		{"incomplete results", 3, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c, pages := searchServer(t, tt.total, tt.incomplete)
+			_, capped, err := c.SearchInactiveRepositories(context.Background(), "acme", true, time.Now())
+			if err != nil {
+				t.Fatalf("SearchInactiveRepositories: %v", err)
+			}
+			if !capped {
+				t.Error("capped = false, want true")
+			}
+			if *pages != 1 {
+				t.Errorf("%d pages requested, want 1", *pages)
+			}
+		})
+	}
+}
+
+func TestMutationFailuresIgnoreForce(t *testing.T) {
+	defer func(force bool) { util.FORCE_PROCESSING = force }(util.FORCE_PROCESSING)
+	util.FORCE_PROCESSING = true
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"name":"tool","owner":{"login":"acme"},"archived":false}`)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"Must have admin rights to Repository."}`)
+	}))
+	if err := c.DeleteRepository(context.Background(), "acme", "tool"); err == nil {
+		t.Error("DeleteRepository() succeeded under -force, want the failure")
+	}
+	if err := c.SetArchiveStatus(context.Background(), "acme", "tool", true); err == nil {
+		t.Error("SetArchiveStatus() succeeded under -force, want the failure")
+	}
+}
+
+func TestForkRepositoryExisting(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     error
+	}{
+		{"earlier fork", `{"name":"tool","fork":true,"parent":{"full_name":"acme/tool"}}`, nil},
+		{"earlier fork, other case", `{"name":"tool","fork":true,"parent":{"full_name":"Acme/Tool"}}`, nil},
+		{"unrelated repository", `{"name":"tool","fork":false}`, ErrNameTaken},
+		{"fork of another repository", `{"name":"tool","fork":true,"parent":{"full_name":"other/tool"}}`, ErrNameTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forks := 0
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/repos/acme-archive/tool":
+					fmt.Fprint(w, tt.existing)
+				case r.Method == http.MethodPost:
+					forks++
+					w.WriteHeader(http.StatusAccepted)
+					fmt.Fprint(w, `{}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			err := c.ForkRepository(context.Background(), "acme", "tool", "acme-archive")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ForkRepository() = %v, want %v", err, tt.want)
+			}
+			if forks != 0 {
+				t.Errorf("%d forks requested, want none", forks)
+			}
+		})
+	}
+}
+
+// forbiddenServer answers every request with 403 and message
+func forbiddenServer(t *testing.T, message string) *Client {
+	t.Helper()
+	return newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `{"message":%q}`, message)
+	}))
+}
+
+func TestListOpenIssuesUnavailable(t *testing.T) {
+	c := forbiddenServer(t, "Issues are disabled for this repository.")
+	issues, err := c.ListOpenIssues(context.Background(), "acme", "tool")
+	if err != nil || len(issues) != 0 {
+		t.Errorf("ListOpenIssues() = %v, %v; want no issues", issues, err)
+	}
+
+	c = forbiddenServer(t, "Resource not accessible by personal access token")
+	if _, err := c.ListOpenIssues(context.Background(), "acme", "tool"); err == nil {
+		t.Error("ListOpenIssues() succeeded without permission, want the error")
+	}
+}
+
+func TestGetLatestOpenPullRequestUnavailable(t *testing.T) {
+	c := forbiddenServer(t, "Pull requests are disabled for this repository.")
+	updated, err := c.GetLatestOpenPullRequest(context.Background(), "acme", "tool")
+	if err != nil || !updated.IsZero() {
+		t.Errorf("GetLatestOpenPullRequest() = %v, %v; want no pull requests", updated, err)
+	}
+
+	c = forbiddenServer(t, "Resource not accessible by personal access token")
+	if _, err := c.GetLatestOpenPullRequest(context.Background(), "acme", "tool"); err == nil {
+		t.Error("GetLatestOpenPullRequest() succeeded without permission, want the error")
+	}
+}
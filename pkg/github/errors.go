@@ -0,0 +1,182 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/util"
+	"github.com/google/go-github/v59/github"
+)
+
+// SSORequiredError reports that the token has not been authorized for an
+// organization that enforces SAML single sign-on
+type SSORequiredError struct {
+	URL string // URL where the token can be authorized, if GitHub provided one
+	Err error
+}
+
+// Error implements the error interface with an actionable message
+func (e *SSORequiredError) Error() string {
+	msg := "the token is not authorized for this organization's SAML single sign-on; authorize it under Settings > Developer settings > Personal access tokens > Configure SSO"
+	if e.URL != "" {
+		msg = fmt.Sprintf("%s, or visit %s", msg, e.URL)
+	}
+	return fmt.Sprintf("%s (%v)", msg, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *SSORequiredError) Unwrap() error {
+	return e.Err
+}
+
+// ssoURL extracts the authorization URL from an X-GitHub-SSO header value
+// such as "required; url=https://github.com/orgs/example/sso?..."
+func ssoURL(header string) string {
+	for _, part := range strings.Split(header, ";") {
+		if url, ok := strings.CutPrefix(strings.TrimSpace(part), "url="); ok {
+			return url
+		}
+	}
+	return ""
+}
+
+// ExplainError converts errors caused by a missing SSO authorization into an
+// SSORequiredError. Other errors are returned unchanged.
+func ExplainError(err error) error {
+	var ssoErr *SSORequiredError
+	if errors.As(err, &ssoErr) {
+		return err
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil && respErr.Response.StatusCode == http.StatusForbidden {
+		if header := respErr.Response.Header.Get("X-GitHub-SSO"); header != "" {
+			return &SSORequiredError{URL: ssoURL(header), Err: err}
+		}
+	}
+	return err
+}
+
+// isUnavailable reports whether a request for an optional repository feature
+// such as issues or Actions failed because the feature is disabled or
+// restricted for the repository: 404 Not Found, 410 Gone for disabled
+// issues, or a 403 that GitHub explains as a disabled feature or one the
+// plan lacks. Other 403s mean the token lacks permission, and are genuine
+// failures like rate limiting, missing SSO authorization and bad
+// credentials, since treating them as no activity would make repositories
+// look inactive.
+func isUnavailable(err error) bool {
+	if isRateLimit(err) {
+		return false
+	}
+
+	var respErr *github.ErrorResponse
+	if !errors.As(err, &respErr) || respErr.Response == nil {
+		return false
+	}
+	switch respErr.Response.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return true
+	case http.StatusForbidden:
+		return respErr.Response.Header.Get("X-GitHub-SSO") == "" && featureDisabled(respErr.Message)
+	}
+	return false
+}
+
+// featureDisabled reports whether a 403 message says the requested feature
+// is turned off for the repository or not included in its owner's plan
+func featureDisabled(message string) bool {
+	msg := strings.ToLower(message)
+	for _, phrase := range []string{"disabled", "not enabled", "upgrade to github pro", "not available"} {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// isForkDisabled reports whether a fork request was refused because forking
+// is disabled for the repository or by organization policy
+func isForkDisabled(err error) bool {
+	var respErr *github.ErrorResponse
+	if !errors.As(err, &respErr) || respErr.Response == nil {
+		return false
+	}
+	switch respErr.Response.StatusCode {
+	case http.StatusForbidden, http.StatusUnprocessableEntity:
+	default:
+		return false
+	}
+	msg := strings.ToLower(respErr.Message)
+	return strings.Contains(msg, "fork") &&
+		(strings.Contains(msg, "disabled") || strings.Contains(msg, "not allowed") || strings.Contains(msg, "cannot be forked"))
+}
+
+// ClassifyError determines the kind of an error returned by the GitHub API.
+// Errors already tagged with a kind keep it; anything unrecognized is
+// treated as an internal error.
+func ClassifyError(err error) util.ErrorKind {
+	if kind, ok := util.KindOf(err); ok {
+		return kind
+	}
+
+	var ssoErr *SSORequiredError
+	if errors.As(ExplainError(err), &ssoErr) {
+		return util.AuthError
+	}
+
+	if DefaultIsRetryable(err) || isRateLimit(err) {
+		return util.TransientError
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		switch respErr.Response.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return util.AuthError
+		}
+	}
+
+	// Timeouts, including expired contexts
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return util.TransientError
+	}
+
+	return util.InternalError
+}
+
+// DefaultIsRetryable is the default transient-error classifier shared by the
+// transport's retries, its circuit breaker and ClassifyError. 5xx responses
+// and network errors are retryable; canceled or expired contexts and client
+// errors such as 404 or 422 are not. Neither are rate limits, which would
+// only fail again before the limit resets; the transport waits for the reset
+// instead, whatever the classifier says.
+func DefaultIsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || isRateLimit(err) {
+		return false
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		return respErr.Response.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isRateLimit reports whether a request was rejected by the primary or a
+// secondary rate limit
+func isRateLimit(err error) bool {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var respErr *github.ErrorResponse
+	return errors.As(err, &rateErr) || errors.As(err, &abuseErr) ||
+		(errors.As(err, &respErr) && respErr.Response != nil && respErr.Response.StatusCode == http.StatusTooManyRequests)
+}
@@ -0,0 +1,95 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/eyedeekay/github-archiver/pkg/util"
+	"github.com/google/go-github/v59/github"
+)
+
+// responseError builds the error the API client returns for a response
+func responseError(status int, message string, header http.Header) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &github.ErrorResponse{
+		Response: &http.Response{StatusCode: status, Header: header, Request: &http.Request{}},
+		Message:  message,
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	sso := http.Header{"X-Github-Sso": []string{"required; url=https://github.com/orgs/acme/sso"}}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"not found", responseError(http.StatusNotFound, "Not Found", nil), true},
+		{"issues disabled", responseError(http.StatusGone, "Issues are disabled for this repo", nil), true},
+		{"actions disabled", responseError(http.StatusForbidden, "Actions is disabled for this repository.", nil), true},
+		{"plan lacks the feature", responseError(http.StatusForbidden, "Upgrade to GitHub Pro or make this repository public to enable this feature.", nil), true},
+		{"missing permission", responseError(http.StatusForbidden, "Resource not accessible by personal access token", nil), false},
+		{"missing admin rights", responseError(http.StatusForbidden, "Must have admin rights to Repository.", nil), false},
+		{"missing SSO authorization", responseError(http.StatusForbidden, "Resource protected by organization SAML enforcement.", sso), false},
+		{"server error", responseError(http.StatusInternalServerError, "Server Error", nil), false},
+		{"rate limited", &github.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}}, false},
+		{"wrapped not found", fmt.Errorf("listing issues: %w", responseError(http.StatusNotFound, "Not Found", nil)), true},
+		{"network error", fmt.Errorf("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := isUnavailable(tt.err); got != tt.want {
+			t.Errorf("%s: isUnavailable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"primary rate limit", &github.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}}, false},
+		{"secondary rate limit", &github.AbuseRateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}}, false},
+		{"429", responseError(http.StatusTooManyRequests, "Too Many Requests", nil), false},
+		{"500", responseError(http.StatusInternalServerError, "Server Error", nil), true},
+		{"502", responseError(http.StatusBadGateway, "Bad Gateway", nil), true},
+		{"503", responseError(http.StatusServiceUnavailable, "Service Unavailable", nil), true},
+		{"network error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"wrapped 502", fmt.Errorf("listing: %w", responseError(http.StatusBadGateway, "Bad Gateway", nil)), true},
+		{"401", responseError(http.StatusUnauthorized, "Bad credentials", nil), false},
+		{"403", responseError(http.StatusForbidden, "Resource not accessible by personal access token", nil), false},
+		{"404", responseError(http.StatusNotFound, "Not Found", nil), false},
+		{"422", responseError(http.StatusUnprocessableEntity, "Validation Failed", nil), false},
+		{"canceled", context.Canceled, false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"canceled network request", fmt.Errorf("%w: %w", context.Canceled, &net.OpError{Op: "read", Err: errors.New("closed")}), false},
+		{"other error", errors.New("invalid fixture"), false},
+	}
+	for _, tt := range tests {
+		if got := DefaultIsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: DefaultIsRetryable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyRateLimit(t *testing.T) {
+	for _, err := range []error{
+		&github.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}},
+		&github.AbuseRateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}},
+		responseError(http.StatusTooManyRequests, "Too Many Requests", nil),
+	} {
+		if kind := ClassifyError(err); kind != util.TransientError {
+			t.Errorf("ClassifyError(%T) = %v, want %v", err, kind, util.TransientError)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package github
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// DefaultMaxRPS is the default request rate across all phases of a run
+const DefaultMaxRPS = 10.0
+
+// limiter spaces requests evenly so the overall rate never exceeds a limit.
+// It is safe for concurrent use; every caller reserves the next free slot.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newLimiter creates a limiter allowing rps requests per second. A
+// non-positive rate disables limiting.
+func newLimiter(rps float64) *limiter {
+	l := &limiter{}
+	l.SetRate(rps)
+	return l
+}
+
+// SetRate changes the limit to rps requests per second, taking effect from
+// the next reservation. A non-positive rate disables limiting.
+func (l *limiter) SetRate(rps float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = 0
+	if rps > 0 {
+		l.interval = time.Duration(float64(time.Second) / rps)
+	}
+}
+
+// Wait blocks until the caller may send a request or ctx is canceled
+func (l *limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.interval == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	return util.SleepCtx(ctx, wait)
+}
@@ -0,0 +1,44 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// maxRedirects is the number of redirects followed for one request, the
+// limit of the default http.Client
+const maxRedirects = 10
+
+// checkRedirect follows the redirects GitHub answers reads of renamed or
+// transferred repositories with. Changes are never redirected: the standard
+// client turns a redirected PATCH, POST or DELETE into a GET, which would
+// report a change to a stale name as a success without making it, and even
+// a 307 or 308 that keeps the method would act on a repository other than
+// the one named. The redirect response is returned to the caller as an error
+// instead.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+	original := via[0]
+	if !idempotent(original) {
+		logger.Warn("Not following the redirect of %s %s to %s; the repository may have been renamed or transferred",
+			original.Method, original.URL.Path, req.URL.Path)
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
+
+// markMoved records the name a repository was requested under if GitHub
+// answered for it under another because it was renamed or transferred.
+// Names differing only in case are the same repository.
+func markMoved(owner, repo string, fetched *Repository) {
+	if strings.EqualFold(owner, fetched.Owner) && strings.EqualFold(repo, fetched.Name) {
+		return
+	}
+	fetched.MovedFrom = owner + "/" + repo
+	logger.Warn("Repository %s/%s has moved to %s/%s, using the new name", owner, repo, fetched.Owner, fetched.Name)
+}
@@ -0,0 +1,73 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// movedServer answers requests for acme/old with a redirect to the renamed
+// repository acme/new, counting the requests that reached it
+func movedServer(t *testing.T, status int) (*Client, *int) {
+	t.Helper()
+	reached := 0
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/old":
+			http.Redirect(w, r, "/repositories/42", status)
+		case "/repositories/42":
+			if r.Method != http.MethodGet {
+				reached++
+			}
+			w.Write([]byte(`{"name":"new","full_name":"acme/new","owner":{"login":"acme"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return c, &reached
+}
+
+func TestGetRepositoryFollowsRename(t *testing.T) {
+	c, _ := movedServer(t, http.StatusMovedPermanently)
+	repo, err := c.GetRepository(context.Background(), "acme", "old")
+	if err != nil {
+		t.Fatalf("GetRepository() = %v", err)
+	}
+	if repo.Owner != "acme" || repo.Name != "new" || repo.MovedFrom != "acme/old" {
+		t.Errorf("GetRepository() = %s/%s moved from %q, want acme/new moved from acme/old", repo.Owner, repo.Name, repo.MovedFrom)
+	}
+}
+
+func TestGetRepositoryCaseIsNotAMove(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"tool","full_name":"acme/tool","owner":{"login":"acme"}}`))
+	}))
+	repo, err := c.GetRepository(context.Background(), "Acme", "Tool")
+	if err != nil {
+		t.Fatalf("GetRepository() = %v", err)
+	}
+	if repo.MovedFrom != "" {
+		t.Errorf("MovedFrom = %q, want none for a difference in case", repo.MovedFrom)
+	}
+}
+
+func TestRedirectedChangesAreRefused(t *testing.T) {
+	for _, status := range []int{
+		http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect,
+	} {
+		c, reached := movedServer(t, status)
+		ctx := context.Background()
+		if err := c.DeleteRepository(ctx, "acme", "old"); err == nil {
+			t.Errorf("DeleteRepository() after %d = nil, want an error", status)
+		}
+		if err := c.SetArchiveStatus(ctx, "acme", "old", true); err == nil {
+			t.Errorf("SetArchiveStatus() after %d = nil, want an error", status)
+		}
+		if *reached != 0 {
+			t.Errorf("%d changes followed a %d redirect", *reached, status)
+		}
+	}
+}
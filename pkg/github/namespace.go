@@ -0,0 +1,102 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// NamespaceType hints what kind of account an archive namespace is, so its
+// existence can be checked with a single request
+type NamespaceType string
+
+const (
+	// NamespaceAuto checks for an organization, then for a user
+	NamespaceAuto NamespaceType = "auto"
+	// NamespaceOrg only checks for an organization
+	NamespaceOrg NamespaceType = "org"
+	// NamespaceUser only checks for a user
+	NamespaceUser NamespaceType = "user"
+)
+
+// ParseNamespaceType converts a type name into a NamespaceType
+func ParseNamespaceType(name string) (NamespaceType, error) {
+	switch t := NamespaceType(name); t {
+	case NamespaceAuto, NamespaceOrg, NamespaceUser:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown namespace type %q (valid: %s, %s, %s)", name, NamespaceAuto, NamespaceOrg, NamespaceUser)
+}
+
+// SetNamespaceType limits archive namespace checks to one kind of account
+func (c *Client) SetNamespaceType(t NamespaceType) {
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+	c.namespaceType = t
+}
+
+// CreateArchiveNamespace checks if the archive organization/user exists.
+// The outcome is cached per namespace for the life of the client, except
+// for failures to check, which are retried on the next call.
+func (c *Client) CreateArchiveNamespace(ctx context.Context, namespace string) error {
+	c.nsMu.Lock()
+	err, cached := c.namespaces[namespace]
+	nsType := c.namespaceType
+	c.nsMu.Unlock()
+	if cached {
+		logger.Debug("Archive namespace %s already checked", namespace)
+		return err
+	}
+
+	exists, err := c.namespaceExists(ctx, namespace, nsType)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		logger.Error("Archive namespace %s does not exist", namespace)
+		// The GitHub API doesn't support programmatic creation of organizations
+		err = fmt.Errorf("%w: '%s' cannot be created automatically. Please create the organization or user account manually", ErrNamespaceNotFound, namespace)
+	}
+
+	c.nsMu.Lock()
+	if c.namespaces == nil {
+		c.namespaces = make(map[string]error)
+	}
+	c.namespaces[namespace] = err
+	c.nsMu.Unlock()
+	return err
+}
+
+// namespaceExists looks the namespace up as the accounts nsType allows
+func (c *Client) namespaceExists(ctx context.Context, namespace string, nsType NamespaceType) (bool, error) {
+	logger.Debug("Checking if archive namespace %s exists", namespace)
+
+	// Check if the namespace exists as an organization
+	if nsType != NamespaceUser {
+		logger.Debug("Checking if %s exists as an organization", namespace)
+		_, resp, err := c.client.Organizations.Get(ctx, namespace)
+		if err == nil {
+			logger.Debug("Archive namespace %s exists as an organization", namespace)
+			return true, nil
+		}
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return false, fmt.Errorf("failed to check archive namespace %s: %w", namespace, err)
+		}
+	}
+
+	// Check if the namespace exists as a user
+	if nsType != NamespaceOrg {
+		logger.Debug("Checking if %s exists as a user", namespace)
+		_, resp, err := c.client.Users.Get(ctx, namespace)
+		if err == nil {
+			logger.Debug("Archive namespace %s exists as a user", namespace)
+			return true, nil
+		}
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return false, fmt.Errorf("failed to check archive namespace %s: %w", namespace, err)
+		}
+	}
+	return false, nil
+}
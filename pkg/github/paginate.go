@@ -0,0 +1,32 @@
+package github
+
+import (
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/google/go-github/v59/github"
+)
+
+// pageSize is the number of items requested per page from list endpoints
+const pageSize = 100
+
+// paginate calls fetch for each page of a listing, starting with the first,
+// until the response reports no further pages. what names the items for log
+// messages. On error the items collected so far are returned with the error.
+func paginate[T any](what string, fetch func(opts github.ListOptions) ([]T, *github.Response, error)) ([]T, error) {
+	var all []T
+	opts := github.ListOptions{PerPage: pageSize}
+	for {
+		logger.Debug("Fetching page %d of %s", opts.Page+1, what)
+		items, resp, err := fetch(opts)
+		if err != nil {
+			return all, err
+		}
+
+		logger.Debug("Retrieved %d %s on page %d", len(items), what, opts.Page+1)
+		all = append(all, items...)
+
+		if resp == nil || resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
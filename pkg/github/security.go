@@ -0,0 +1,91 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/google/go-github/v59/github"
+)
+
+// dependabotLogin is the author of Dependabot's pull requests
+const dependabotLogin = "dependabot[bot]"
+
+// securityScanDepth bounds how many recently updated pull requests or
+// advisories are examined per repository
+const securityScanDepth = 30
+
+// SecurityActivity is a piece of security maintenance on a repository
+type SecurityActivity struct {
+	Updated time.Time
+	Ref     string // the pull request or advisory, e.g. "Dependabot PR #12"
+}
+
+// GetLatestDependabotPullRequest returns the most recently updated pull
+// request opened by Dependabot, open or not, among the repository's most
+// recently updated ones. Repositories with pull requests unavailable are
+// treated as having none.
+func (c *Client) GetLatestDependabotPullRequest(ctx context.Context, owner, repo string) (SecurityActivity, error) {
+	logger.Debug("Checking for Dependabot pull requests in %s/%s", owner, repo)
+
+	opts := &github.PullRequestListOptions{
+		State:     "all",
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: securityScanDepth,
+		},
+	}
+	pulls, _, err := c.client.PullRequests.List(ctx, owner, repo, opts)
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Debug("Pull requests unavailable for %s/%s, treating as none", owner, repo)
+			return SecurityActivity{}, nil
+		}
+		logger.Error("Failed to list pull requests for %s/%s: %v", owner, repo, err)
+		return SecurityActivity{}, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+	for _, pull := range pulls {
+		if pull.GetUser().GetLogin() == dependabotLogin {
+			return SecurityActivity{
+				Updated: pull.GetUpdatedAt().Time,
+				Ref:     fmt.Sprintf("Dependabot PR #%d", pull.GetNumber()),
+			}, nil
+		}
+	}
+	return SecurityActivity{}, nil
+}
+
+// GetLatestSecurityAdvisory returns the most recently updated security
+// advisory of the repository that is not closed. Repositories with security
+// advisories disabled, or that the token cannot see them for, are treated as
+// having none.
+func (c *Client) GetLatestSecurityAdvisory(ctx context.Context, owner, repo string) (SecurityActivity, error) {
+	logger.Debug("Checking for security advisories in %s/%s", owner, repo)
+
+	opts := &github.ListRepositorySecurityAdvisoriesOptions{
+		ListCursorOptions: github.ListCursorOptions{PerPage: securityScanDepth},
+		Sort:              "updated",
+		Direction:         "desc",
+	}
+	advisories, _, err := c.client.SecurityAdvisories.ListRepositorySecurityAdvisories(ctx, owner, repo, opts)
+	if err != nil {
+		if isUnavailable(err) {
+			logger.Debug("Security advisories unavailable for %s/%s, treating as none", owner, repo)
+			return SecurityActivity{}, nil
+		}
+		logger.Error("Failed to list security advisories for %s/%s: %v", owner, repo, err)
+		return SecurityActivity{}, fmt.Errorf("failed to list security advisories: %w", err)
+	}
+	for _, advisory := range advisories {
+		if advisory.GetState() == "closed" {
+			continue
+		}
+		return SecurityActivity{
+			Updated: advisory.GetUpdatedAt().Time,
+			Ref:     fmt.Sprintf("advisory %s (%s)", advisory.GetGHSAID(), advisory.GetState()),
+		}, nil
+	}
+	return SecurityActivity{}, nil
+}
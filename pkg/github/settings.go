@@ -0,0 +1,132 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/google/go-github/v59/github"
+)
+
+// Settings is a snapshot of the repository configuration that deleting a
+// repository loses. Sections the token cannot read are named in Unavailable.
+type Settings struct {
+	Owner            string          `json:"owner"`
+	Name             string          `json:"name"`
+	ExportedAt       time.Time       `json:"exported_at"`
+	DefaultBranch    string          `json:"default_branch,omitempty"`
+	Webhooks         []Webhook       `json:"webhooks"`
+	BranchProtection json.RawMessage `json:"branch_protection,omitempty"`
+	Collaborators    []Collaborator  `json:"collaborators"`
+	SecretNames      []string        `json:"secret_names"`
+	Unavailable      []string        `json:"unavailable,omitempty"`
+}
+
+// Webhook describes a repository webhook. Its secret is never exported.
+type Webhook struct {
+	ID          int64    `json:"id"`
+	URL         string   `json:"url,omitempty"`
+	ContentType string   `json:"content_type,omitempty"`
+	Events      []string `json:"events"`
+	Active      bool     `json:"active"`
+}
+
+// Collaborator is a user with access to a repository and their role
+type Collaborator struct {
+	Login string `json:"login"`
+	Role  string `json:"role,omitempty"`
+}
+
+// GetSettings exports the webhooks, default branch protection,
+// collaborators and Actions secret names of a repository. Secret values
+// cannot be read through the API and are never exported. Sections the token
+// lacks access to are skipped and listed in Unavailable; other failures are
+// returned.
+func (c *Client) GetSettings(ctx context.Context, owner, repo, branch string) (Settings, error) {
+	logger.Debug("Exporting settings of %s/%s", owner, repo)
+	settings := Settings{
+		Owner:         owner,
+		Name:          repo,
+		ExportedAt:    time.Now().UTC(),
+		DefaultBranch: branch,
+		Webhooks:      []Webhook{},
+		Collaborators: []Collaborator{},
+		SecretNames:   []string{},
+	}
+
+	// unavailable records a section the token cannot read
+	unavailable := func(section string, err error) error {
+		if !isUnavailable(err) {
+			logger.Error("Failed to export %s of %s/%s: %v", section, owner, repo, err)
+			return fmt.Errorf("failed to export %s: %w", section, err)
+		}
+		logger.Debug("Cannot read %s of %s/%s: %v", section, owner, repo, err)
+		settings.Unavailable = append(settings.Unavailable, section)
+		return nil
+	}
+
+	hooks, err := paginate("webhooks", func(page github.ListOptions) ([]*github.Hook, *github.Response, error) {
+		return c.client.Repositories.ListHooks(ctx, owner, repo, &page)
+	})
+	if err != nil {
+		if err := unavailable("webhooks", err); err != nil {
+			return settings, err
+		}
+	}
+	for _, hook := range hooks {
+		webhook := Webhook{ID: hook.GetID(), Events: hook.Events, Active: hook.GetActive()}
+		webhook.URL, _ = hook.Config["url"].(string)
+		webhook.ContentType, _ = hook.Config["content_type"].(string)
+		settings.Webhooks = append(settings.Webhooks, webhook)
+	}
+
+	// Unprotected branches are reported as not found
+	if branch != "" {
+		protection, resp, err := c.client.Repositories.GetBranchProtection(ctx, owner, repo, branch)
+		switch {
+		case err == nil:
+			settings.BranchProtection, err = json.Marshal(protection)
+			if err != nil {
+				return settings, fmt.Errorf("failed to encode branch protection: %w", err)
+			}
+		case resp != nil && resp.StatusCode == http.StatusNotFound:
+		default:
+			if err := unavailable("branch protection", err); err != nil {
+				return settings, err
+			}
+		}
+	}
+
+	users, err := paginate("collaborators", func(page github.ListOptions) ([]*github.User, *github.Response, error) {
+		return c.client.Repositories.ListCollaborators(ctx, owner, repo, &github.ListCollaboratorsOptions{ListOptions: page})
+	})
+	if err != nil {
+		if err := unavailable("collaborators", err); err != nil {
+			return settings, err
+		}
+	}
+	for _, user := range users {
+		settings.Collaborators = append(settings.Collaborators, Collaborator{Login: user.GetLogin(), Role: user.GetRoleName()})
+	}
+
+	secrets, err := paginate("secrets", func(page github.ListOptions) ([]*github.Secret, *github.Response, error) {
+		list, resp, err := c.client.Actions.ListRepoSecrets(ctx, owner, repo, &page)
+		if err != nil {
+			return nil, resp, err
+		}
+		return list.Secrets, resp, nil
+	})
+	if err != nil {
+		if err := unavailable("secrets", err); err != nil {
+			return settings, err
+		}
+	}
+	for _, secret := range secrets {
+		settings.SecretNames = append(settings.SecretNames, secret.Name)
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,41 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+)
+
+// Verification is GitHub's signature verification of a commit
+type Verification struct {
+	SHA      string
+	Signed   bool   // whether the commit carries a signature
+	Verified bool   // whether GitHub verified the signature
+	Reason   string // GitHub's reason, such as valid or unsigned
+}
+
+// GetHeadVerification returns the signature verification of the commit at
+// the head of a branch. An empty repository has no head and yields the zero
+// Verification.
+func (c *Client) GetHeadVerification(ctx context.Context, owner, repo, branch string) (Verification, error) {
+	logger.Debug("Checking the head signature of %s in %s/%s", branch, owner, repo)
+
+	commit, resp, err := c.client.Repositories.GetCommit(ctx, owner, repo, branch, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			logger.Debug("Repository %s/%s is empty", owner, repo)
+			return Verification{}, nil
+		}
+		logger.Error("Failed to get the head commit of %s/%s: %v", owner, repo, err)
+		return Verification{}, fmt.Errorf("failed to get head commit: %w", err)
+	}
+	v := commit.GetCommit().GetVerification()
+	return Verification{
+		SHA:      commit.GetSHA(),
+		Signed:   v.GetSignature() != "",
+		Verified: v.GetVerified(),
+		Reason:   v.GetReason(),
+	}, nil
+}
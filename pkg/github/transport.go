@@ -0,0 +1,322 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/logger"
+	"github.com/eyedeekay/github-archiver/pkg/metrics"
+	"github.com/eyedeekay/github-archiver/pkg/util"
+	"github.com/google/go-github/v59/github"
+)
+
+// Retry defaults
+const (
+	DefaultRetryBudget      = 50
+	DefaultBreakerThreshold = 10
+	maxAttempts             = 3
+	retryBaseDelay          = time.Second
+
+	// maxRateLimitWait is the longest the transport waits for a rate limit
+	// to reset before failing the request with the rate limit error
+	maxRateLimitWait = 15 * time.Minute
+	// secondaryRateLimitWait is the wait GitHub advises after a secondary
+	// rate limit that does not say how long to wait
+	secondaryRateLimitWait = time.Minute
+)
+
+// ErrUnhealthy is returned for every request once the circuit breaker has
+// tripped after too many consecutive transient failures
+var ErrUnhealthy = util.NewError(util.TransientError, errors.New("GitHub appears unhealthy: too many consecutive transient failures, aborting"))
+
+// RateUsage summarizes the core API quota consumed by a client
+type RateUsage struct {
+	Requests  int           // requests counted against the core rate limit
+	Start     int           // quota remaining before the first request
+	Remaining int           // quota remaining after the latest request
+	Limit     int           // quota per rate limit window
+	Reset     time.Time     // when the current window resets
+	Resets    int           // number of window resets observed during the run
+	Retries   int           // requests retried after a transient failure
+	Backoff   time.Duration // total time spent waiting before retries
+}
+
+// Consumed returns the quota used, based on the remaining counts when the
+// window did not reset and on the request count otherwise
+func (u RateUsage) Consumed() int {
+	if u.Resets == 0 && u.Start >= u.Remaining {
+		return u.Start - u.Remaining
+	}
+	return u.Requests
+}
+
+// transport is an http.RoundTripper that records rate limit headers, waits
+// for rate limits to reset, retries idempotent requests on transient
+// failures within a run-wide budget, and trips a circuit breaker after too
+// many consecutive transient failures
+type transport struct {
+	base    http.RoundTripper
+	limiter *limiter
+	metrics *metrics.Counters
+
+	mu               sync.Mutex
+	usage            RateUsage
+	seen             bool
+	retryBudget      int
+	breakerThreshold int
+	consecutive      int
+	tripped          bool
+	ssoWarned        bool
+	isRetryable      func(error) bool
+	auditor          Auditor
+	auditUser        string
+	auditSeq         uint64
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if t.isTripped() {
+			return nil, ErrUnhealthy
+		}
+		if err := t.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		audited, err := t.auditSending(req)
+		if err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+
+		t.metrics.Inc(metrics.APICalls)
+		resp, err := t.base.RoundTrip(req)
+		if err == nil {
+			t.record(resp)
+			t.checkSSO(resp)
+			t.countThrottled(resp)
+		}
+		t.auditAnswered(audited, resp, err)
+
+		// Rate limits say when to try again, and are not a sign of poor
+		// health, so they neither back off blindly nor count toward the
+		// breaker
+		if wait, limited := rateLimitWait(resp); limited {
+			if !idempotent(req) || attempt >= maxAttempts || wait > maxRateLimitWait {
+				logger.Warn("Rate limited on %s %s until %s", req.Method, req.URL.Path, time.Now().Add(wait).Format(time.RFC3339))
+				return resp, err
+			}
+			logger.Warn("Rate limited on %s %s, waiting %v for the limit to reset", req.Method, req.URL.Path, wait.Round(time.Second))
+			resp.Body.Close()
+			if err := t.backoff(req.Context(), wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		transient := t.isTransient(req, resp, err)
+		t.observe(transient)
+		if !transient || !idempotent(req) || attempt >= maxAttempts || !t.takeRetry() {
+			return resp, err
+		}
+
+		delay := retryBaseDelay * time.Duration(1<<(attempt-1))
+		logger.Debug("Transient failure for %s %s, retrying in %v (attempt %d/%d)", req.Method, req.URL.Path, delay, attempt+1, maxAttempts)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if err := t.backoff(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isTransient reports whether a request failed in a way worth retrying,
+// according to the configured classifier. Error responses are converted to
+// the errors the API client would return, leaving the body readable.
+func (t *transport) isTransient(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err == nil {
+		if resp.StatusCode < 400 {
+			return false
+		}
+		err = github.CheckResponse(resp)
+	}
+	t.mu.Lock()
+	isRetryable := t.isRetryable
+	t.mu.Unlock()
+	if isRetryable == nil {
+		isRetryable = DefaultIsRetryable
+	}
+	return isRetryable(err)
+}
+
+// idempotent reports whether a request can safely be sent again
+func idempotent(req *http.Request) bool {
+	return req.Method == http.MethodGet || req.Method == http.MethodHead
+}
+
+// isTripped reports whether the circuit breaker is open
+func (t *transport) isTripped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tripped
+}
+
+// observe updates the consecutive failure count, tripping the breaker when
+// it reaches the threshold and resetting it on success
+func (t *transport) observe(transient bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !transient {
+		t.consecutive = 0
+		return
+	}
+	t.consecutive++
+	if t.breakerThreshold > 0 && t.consecutive >= t.breakerThreshold && !t.tripped {
+		t.tripped = true
+		logger.Error("Circuit breaker tripped after %d consecutive transient failures", t.consecutive)
+	}
+}
+
+// takeRetry consumes one retry from the run-wide budget
+func (t *transport) takeRetry() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.retryBudget <= 0 {
+		logger.Debug("Retry budget exhausted")
+		return false
+	}
+	t.retryBudget--
+	t.usage.Retries++
+	return true
+}
+
+// backoff waits before a retry and records the time spent
+func (t *transport) backoff(ctx context.Context, delay time.Duration) error {
+	start := time.Now()
+	err := util.SleepCtx(ctx, delay)
+
+	elapsed := time.Since(start)
+	t.metrics.Add(metrics.Backoff, elapsed.Milliseconds())
+	t.mu.Lock()
+	t.usage.Backoff += elapsed
+	t.mu.Unlock()
+	return err
+}
+
+// countThrottled counts a response rejected by the primary or a secondary
+// rate limit. A 429 that GitHub does not describe is counted as secondary.
+func (t *transport) countThrottled(resp *http.Response) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	err := github.CheckResponse(resp)
+	switch {
+	case errors.As(err, &rateErr):
+		t.metrics.Inc(metrics.PrimaryHits)
+	case errors.As(err, &abuseErr), resp.StatusCode == http.StatusTooManyRequests:
+		t.metrics.Inc(metrics.SecondaryHits)
+	}
+}
+
+// rateLimitWait reports whether a response was rejected by the primary or a
+// secondary rate limit, and how long to wait before sending the request
+// again: until the primary limit's reset, or as long as Retry-After says.
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return 0, false
+	}
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var wait time.Duration
+	err := github.CheckResponse(resp)
+	switch {
+	case errors.As(err, &rateErr):
+		wait = time.Until(rateErr.Rate.Reset.Time) + time.Second
+	case errors.As(err, &abuseErr) && abuseErr.RetryAfter != nil:
+		wait = *abuseErr.RetryAfter
+	case errors.As(err, &abuseErr), resp.StatusCode == http.StatusTooManyRequests:
+		wait = secondaryRateLimitWait
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		}
+	default:
+		return 0, false
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// checkSSO logs an actionable message the first time a request is rejected
+// because the token lacks SAML SSO authorization
+func (t *transport) checkSSO(resp *http.Response) {
+	if resp.StatusCode != http.StatusForbidden {
+		return
+	}
+	header := resp.Header.Get("X-GitHub-SSO")
+	if header == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ssoWarned {
+		return
+	}
+	t.ssoWarned = true
+	if url := ssoURL(header); url != "" {
+		logger.Error("The token is not authorized for SAML SSO with this organization; authorize it at %s", url)
+	} else {
+		logger.Error("The token is not authorized for SAML SSO with this organization; authorize it under Settings > Developer settings > Personal access tokens > Configure SSO")
+	}
+}
+
+// record updates the usage from a response's rate limit headers
+func (t *transport) record(resp *http.Response) {
+	if resource := resp.Header.Get("X-RateLimit-Resource"); resource != "" && resource != "core" {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	resetUnix, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	reset := time.Unix(resetUnix, 0)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.seen {
+		t.seen = true
+		t.usage.Start = remaining + 1
+	} else if !reset.Equal(t.usage.Reset) {
+		t.usage.Resets++
+	}
+	t.usage.Requests++
+	t.usage.Remaining = remaining
+	t.usage.Limit = limit
+	t.usage.Reset = reset
+}
+
+// snapshot returns a copy of the recorded usage
+func (t *transport) snapshot() RateUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
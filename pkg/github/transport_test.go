@@ -0,0 +1,142 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v59/github"
+)
+
+// rateLimitedServer rejects the first request for acme/tool with limit and
+// serves the repository afterwards, counting the requests it receives
+func rateLimitedServer(t *testing.T, limit func(w http.ResponseWriter)) (*Client, *int) {
+	t.Helper()
+	requests := 0
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			limit(w)
+			return
+		}
+		fmt.Fprint(w, `{"name":"tool","owner":{"login":"acme"}}`)
+	}))
+	c.SetRetryPolicy(DefaultRetryBudget, 1)
+	return c, &requests
+}
+
+func TestRateLimitWaitsForRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit func(w http.ResponseWriter)
+	}{
+		{"secondary rate limit", func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit.","documentation_url":"https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`)
+		}},
+		{"too many requests", func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}},
+		{"primary rate limit", func(w http.ResponseWriter) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix()+1, 10))
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, requests := rateLimitedServer(t, tt.limit)
+			start := time.Now()
+			if _, err := c.GetRepository(context.Background(), "acme", "tool"); err != nil {
+				t.Fatalf("GetRepository() = %v, want success after the wait", err)
+			}
+			if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+				t.Errorf("retried after %v, want the advised second", elapsed)
+			}
+			if *requests != 2 {
+				t.Errorf("%d requests sent, want 2", *requests)
+			}
+			if c.transport.isTripped() {
+				t.Error("a rate limit tripped the circuit breaker")
+			}
+		})
+	}
+}
+
+func TestRateLimitBeyondMaxWaitFails(t *testing.T) {
+	c, requests := rateLimitedServer(t, func(w http.ResponseWriter) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+	})
+	start := time.Now()
+	_, err := c.GetRepository(context.Background(), "acme", "tool")
+	var rateErr *github.RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("GetRepository() = %v, want the rate limit error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("failed after %v, want no wait", elapsed)
+	}
+	if *requests != 1 {
+		t.Errorf("%d requests sent, want 1", *requests)
+	}
+	if c.transport.isTripped() {
+		t.Error("a rate limit tripped the circuit breaker")
+	}
+	if errors.Is(err, ErrUnhealthy) {
+		t.Error("a rate limit was reported as an unhealthy API")
+	}
+}
+
+func TestRateLimitIgnoresClassifier(t *testing.T) {
+	c, requests := rateLimitedServer(t, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	c.SetIsRetryable(func(error) bool { return true })
+	start := time.Now()
+	if _, err := c.GetRepository(context.Background(), "acme", "tool"); err != nil {
+		t.Fatalf("GetRepository() = %v, want success after the wait", err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("retried after %v, want the advised second", elapsed)
+	}
+	if *requests != 2 {
+		t.Errorf("%d requests sent, want 2", *requests)
+	}
+}
+
+func TestSetMaxRPSWhileRequesting(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"tool","owner":{"login":"acme"}}`)
+	}))
+	c.SetMaxRPS(0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := c.GetRepository(context.Background(), "acme", "tool"); err != nil {
+					t.Errorf("GetRepository: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		c.SetMaxRPS(float64(1000 + i))
+	}
+	wg.Wait()
+}
@@ -5,7 +5,10 @@ import (
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/eyedeekay/github-archiver/pkg/util"
 )
@@ -31,9 +34,50 @@ var levelNames = map[LogLevel]string{
 	SilentLevel: "SILENT",
 }
 
+// ParseLevel converts a level name such as "debug" or "warn" into a LogLevel
+func ParseLevel(name string) (LogLevel, error) {
+	var valid []string
+	for level := DebugLevel; level <= SilentLevel; level++ {
+		levelName := strings.ToLower(strings.TrimSpace(levelNames[level]))
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+		valid = append(valid, levelName)
+	}
+	return InfoLevel, fmt.Errorf("invalid log level %q (valid: %s)", name, strings.Join(valid, ", "))
+}
+
+// Format selects how log lines are written
+type Format int
+
+const (
+	// TextFormat writes "[timestamp] LEVEL: message" lines
+	TextFormat Format = iota
+	// LogfmtFormat writes ts=... level=... msg="..." key=value lines
+	LogfmtFormat
+)
+
+var formatNames = map[Format]string{
+	TextFormat:   "text",
+	LogfmtFormat: "logfmt",
+}
+
+// ParseFormat converts a format name such as "text" or "logfmt" into a Format
+func ParseFormat(name string) (Format, error) {
+	var valid []string
+	for format := TextFormat; format <= LogfmtFormat; format++ {
+		if strings.EqualFold(name, formatNames[format]) {
+			return format, nil
+		}
+		valid = append(valid, formatNames[format])
+	}
+	return TextFormat, fmt.Errorf("invalid log format %q (valid: %s)", name, strings.Join(valid, ", "))
+}
+
 // Logger provides structured logging for the application
 type Logger struct {
 	level  LogLevel
+	format Format
 	writer io.Writer
 	logger *log.Logger
 }
@@ -52,18 +96,46 @@ func (l *Logger) SetLevel(level LogLevel) {
 	l.level = level
 }
 
+// SetFormat changes the format log lines are written in
+func (l *Logger) SetFormat(format Format) {
+	l.format = format
+}
+
+// SetOutput changes the writer log messages are written to
+func (l *Logger) SetOutput(writer io.Writer) {
+	l.writer = writer
+	l.logger.SetOutput(writer)
+}
+
 // log formats and writes a log message if the level is sufficient
 func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 	if level < l.level {
 		return
 	}
 
-	// Format with timestamp, level name, and message
-	timestamp := time.Now().Format("2006/01/02 15:04:05")
+	now := time.Now()
 	levelStr := levelNames[level]
 	message := fmt.Sprintf(format, args...)
 
-	l.logger.Printf("[%s] %s: %s", timestamp, levelStr, message)
+	if l.format == LogfmtFormat {
+		l.logger.Printf("ts=%s level=%s msg=%s",
+			now.Format(time.RFC3339), strings.ToLower(strings.TrimSpace(levelStr)), logfmtValue(message))
+		return
+	}
+
+	// Format with timestamp, level name, and message
+	l.logger.Printf("[%s] %s: %s", now.Format("2006/01/02 15:04:05"), levelStr, message)
+}
+
+// logfmtValue returns a logfmt value, quoted and escaped if it is empty or
+// contains spaces, quotes, equals signs or control characters
+func logfmtValue(value string) string {
+	if value == "" || strings.IndexFunc(value, func(r rune) bool {
+		return r == '"' || r == '=' || r == '\\' || unicode.IsSpace(r) || unicode.IsControl(r)
+	}) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
 }
 
 // Debug logs a debug message
@@ -100,6 +172,16 @@ func SetDefaultLevel(level LogLevel) {
 	defaultLogger.SetLevel(level)
 }
 
+// SetDefaultFormat sets the log format for the default logger
+func SetDefaultFormat(format Format) {
+	defaultLogger.SetFormat(format)
+}
+
+// SetDefaultOutput sets the writer for the default logger
+func SetDefaultOutput(writer io.Writer) {
+	defaultLogger.SetOutput(writer)
+}
+
 // Debug logs to the default logger
 func Debug(format string, args ...interface{}) {
 	defaultLogger.Debug(format, args...)
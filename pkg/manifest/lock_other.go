@@ -0,0 +1,14 @@
+//go:build !unix
+
+package manifest
+
+import "os"
+
+// lockFile is a no-op on platforms without advisory file locks; records
+// are still serialized within the process
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without advisory file locks
+func unlockFile(f *os.File) {}
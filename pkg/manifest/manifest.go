@@ -0,0 +1,100 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record describes what happened to a single repository. Records are
+// written as JSON Lines and can be fed back to -archive-from.
+type Record struct {
+	Time         time.Time `json:"time"`
+	Owner        string    `json:"owner"`
+	Name         string    `json:"name"`
+	MovedFrom    string    `json:"moved_from,omitempty"`
+	Strategy     string    `json:"strategy"`
+	Status       string    `json:"status"`
+	Namespace    string    `json:"namespace,omitempty"`
+	ArchivedName string    `json:"archived_name,omitempty"`
+	Deleted      bool      `json:"deleted"`
+	Visibility   string    `json:"visibility,omitempty"`
+	AtticPath    string    `json:"attic_path,omitempty"`
+	Readers      []string  `json:"collaborators,omitempty"`
+	GistURL      string    `json:"gist_url,omitempty"`
+	SettingsFile string    `json:"settings_file,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// Writer appends records to a manifest file. A nil Writer discards records.
+// It is safe for concurrent use: each record is written as a single line
+// under a mutex and, where supported, an exclusive lock on the file, so
+// concurrent workers and processes sharing the manifest never interleave.
+type Writer struct {
+	mu  sync.Mutex
+	f   *os.File
+	buf bytes.Buffer
+}
+
+// Open opens a manifest file for appending, creating it if needed
+func Open(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	return &Writer{f: f}, nil
+}
+
+// Write appends a record, stamping it with the current time if unset
+func (w *Writer) Write(record Record) error {
+	if w == nil {
+		return nil
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Encode first so a partial record never reaches the file
+	w.buf.Reset()
+	if err := json.NewEncoder(&w.buf).Encode(record); err != nil {
+		return fmt.Errorf("failed to encode manifest record: %w", err)
+	}
+
+	if err := lockFile(w.f); err != nil {
+		return fmt.Errorf("failed to lock manifest: %w", err)
+	}
+	defer unlockFile(w.f)
+	if _, err := w.f.Write(w.buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write manifest record: %w", err)
+	}
+	return nil
+}
+
+// Sync flushes written records to disk, checkpointing long runs
+func (w *Writer) Sync() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync manifest: %w", err)
+	}
+	return nil
+}
+
+// Close closes the manifest file
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Close()
+}
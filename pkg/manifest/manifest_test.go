@@ -0,0 +1,85 @@
+package manifest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriterConcurrent(t *testing.T) {
+	const writers, records = 16, 200
+	path := filepath.Join(t.TempDir(), "manifest.jsonl")
+
+	w, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Long error messages make interleaved writes likely if unsynchronized
+	padding := strings.Repeat("x", 4096)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for n := 0; n < records; n++ {
+				record := Record{
+					Owner:  fmt.Sprintf("worker-%d", worker),
+					Name:   fmt.Sprintf("repo-%d", n),
+					Status: "failed",
+					Error:  padding,
+				}
+				if err := w.Write(record); err != nil {
+					t.Errorf("Write() = %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("corrupt manifest line %d: %v", len(seen)+1, err)
+		}
+		key := record.Owner + "/" + record.Name
+		if seen[key] {
+			t.Errorf("record %s written twice", key)
+		}
+		if record.Time.IsZero() || record.Error != padding {
+			t.Errorf("record %s is incomplete", key)
+		}
+		seen[key] = true
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != writers*records {
+		t.Errorf("manifest has %d records, want %d", len(seen), writers*records)
+	}
+}
+
+func TestNilWriter(t *testing.T) {
+	var w *Writer
+	if err := w.Write(Record{Owner: "acme", Name: "tool"}); err != nil {
+		t.Errorf("Write() = %v, want nil", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+}
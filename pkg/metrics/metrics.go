@@ -0,0 +1,170 @@
+package metrics
+
+import "sync"
+
+// Counter identifies a run statistic
+type Counter int
+
+// Run statistics
+const (
+	Total         Counter = iota // repositories considered
+	Inactive                     // repositories selected for archiving
+	Archived                     // repositories archived
+	Skipped                      // repositories spared by a guard or filter
+	Failed                       // repositories that could not be analyzed or archived
+	BytesBackedUp                // size of repositories copied into an archive namespace
+	APICalls                     // GitHub API requests sent, including retries
+	TimedOut                     // failed repositories abandoned after the per-repository timeout
+	Reclaimed                    // size of deleted originals, the estimated storage reclaimed
+	PrimaryHits                  // responses rejected by the primary rate limit
+	SecondaryHits                // responses rejected by a secondary (abuse) rate limit
+	Backoff                      // milliseconds spent sleeping before retries
+	Deferred                     // candidates left for a later run at the runtime deadline
+	numCounters
+)
+
+// Counters holds the statistics of a run. It is safe for concurrent use, and
+// a nil Counters discards updates.
+type Counters struct {
+	mu       sync.Mutex
+	values   [numCounters]int64
+	failures []Failure
+	phase    string
+	phases   []*Throttling
+}
+
+// Throttling summarizes rate limiting within one phase of a run
+type Throttling struct {
+	Phase         string `json:"phase"`
+	PrimaryHits   int64  `json:"primary_rate_limit_hits"`
+	SecondaryHits int64  `json:"secondary_rate_limit_hits"`
+	BackoffMillis int64  `json:"backoff_ms"`
+}
+
+// Failure describes a repository that could not be analyzed or archived
+type Failure struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// New creates a set of counters starting at zero
+func New() *Counters {
+	return &Counters{}
+}
+
+// Add increases a counter by n
+func (c *Counters) Add(counter Counter, n int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[counter] += n
+	c.tally(counter, n)
+}
+
+// SetPhase starts a phase of the run, such as listing or archiving, under
+// which later rate limiting is tallied
+func (c *Counters) SetPhase(phase string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.phase = phase
+}
+
+// tally adds to the throttling counters of the current phase, if any. The
+// caller holds the lock.
+func (c *Counters) tally(counter Counter, n int64) {
+	if c.phase == "" || (counter != PrimaryHits && counter != SecondaryHits && counter != Backoff) {
+		return
+	}
+	var phase *Throttling
+	for _, p := range c.phases {
+		if p.Phase == c.phase {
+			phase = p
+		}
+	}
+	if phase == nil {
+		phase = &Throttling{Phase: c.phase}
+		c.phases = append(c.phases, phase)
+	}
+	switch counter {
+	case PrimaryHits:
+		phase.PrimaryHits += n
+	case SecondaryHits:
+		phase.SecondaryHits += n
+	case Backoff:
+		phase.BackoffMillis += n
+	}
+}
+
+// Inc increases a counter by one
+func (c *Counters) Inc(counter Counter) {
+	c.Add(counter, 1)
+}
+
+// Fail counts a failed repository and records why it failed
+func (c *Counters) Fail(owner, name string, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[Failed]++
+	c.failures = append(c.failures, Failure{Owner: owner, Name: name, Error: err.Error()})
+}
+
+// Snapshot is a point-in-time copy of the counters
+type Snapshot struct {
+	Total         int64     `json:"total"`
+	Inactive      int64     `json:"inactive"`
+	Archived      int64     `json:"archived"`
+	Skipped       int64     `json:"skipped"`
+	Failed        int64     `json:"failed"`
+	BytesBackedUp int64     `json:"bytes_backed_up"`
+	APICalls      int64     `json:"api_calls"`
+	TimedOut      int64     `json:"timed_out"`
+	Reclaimed     int64     `json:"storage_reclaimed_bytes"`
+	PrimaryHits   int64     `json:"primary_rate_limit_hits"`
+	SecondaryHits int64     `json:"secondary_rate_limit_hits"`
+	BackoffMillis int64     `json:"backoff_ms"`
+	Deferred      int64     `json:"deferred"`
+	Failures      []Failure `json:"failures"`
+
+	// Throttling breaks rate limiting down by phase, in the order the
+	// phases were first throttled
+	Throttling []Throttling `json:"throttling_by_phase"`
+}
+
+// Snapshot returns the current value of every counter
+func (c *Counters) Snapshot() Snapshot {
+	if c == nil {
+		return Snapshot{Throttling: []Throttling{}, Failures: []Failure{}}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	phases := make([]Throttling, 0, len(c.phases))
+	for _, p := range c.phases {
+		phases = append(phases, *p)
+	}
+	return Snapshot{
+		Total:         c.values[Total],
+		Inactive:      c.values[Inactive],
+		Archived:      c.values[Archived],
+		Skipped:       c.values[Skipped],
+		Failed:        c.values[Failed],
+		BytesBackedUp: c.values[BytesBackedUp],
+		APICalls:      c.values[APICalls],
+		TimedOut:      c.values[TimedOut],
+		Reclaimed:     c.values[Reclaimed],
+		PrimaryHits:   c.values[PrimaryHits],
+		SecondaryHits: c.values[SecondaryHits],
+		BackoffMillis: c.values[Backoff],
+		Deferred:      c.values[Deferred],
+		Throttling:    phases,
+		Failures:      append([]Failure{}, c.failures...),
+	}
+}
@@ -0,0 +1,103 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Kinds of status change between two reports
+const (
+	ChangeNewlyInactive = "newly-inactive" // selected now, but not before
+	ChangeActiveAgain   = "active-again"   // selected before, kept now
+	ChangeArchived      = "archived"       // archived since the earlier report
+	ChangeStatus        = "status-changed" // any other status change
+)
+
+// Diff is the delta between an earlier and a later report. Repositories
+// are identified by owner and name; each list is sorted by them.
+type Diff struct {
+	// Added holds entries only in the later report
+	Added []Entry `json:"added"`
+	// Removed holds entries only in the earlier report
+	Removed []Entry `json:"removed"`
+	// Changed holds repositories whose status differs between the reports
+	Changed []Change `json:"changed"`
+}
+
+// Change describes a repository whose status differs between two reports
+type Change struct {
+	Owner  string `json:"owner"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Before Entry  `json:"before"`
+	After  Entry  `json:"after"`
+}
+
+// ReadJSON reads a report written by RenderJSON, e.g. with -report-file
+func ReadJSON(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Compare computes the delta from the before report to the after report
+func Compare(before, after []Entry) Diff {
+	key := func(e Entry) string { return strings.ToLower(e.Owner + "/" + e.Name) }
+	earlier := make(map[string]Entry, len(before))
+	for _, e := range before {
+		earlier[key(e)] = e
+	}
+
+	diff := Diff{Added: []Entry{}, Removed: []Entry{}, Changed: []Change{}}
+	seen := make(map[string]bool, len(after))
+	for _, e := range after {
+		k := key(e)
+		seen[k] = true
+		old, ok := earlier[k]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, e)
+		case old.Status != e.Status:
+			diff.Changed = append(diff.Changed, Change{
+				Owner:  e.Owner,
+				Name:   e.Name,
+				Kind:   changeKind(old.Status, e.Status),
+				Before: old,
+				After:  e,
+			})
+		}
+	}
+	for _, e := range before {
+		if !seen[key(e)] {
+			diff.Removed = append(diff.Removed, e)
+		}
+	}
+
+	byName := func(a, b Entry) bool { return key(a) < key(b) }
+	sort.Slice(diff.Added, func(i, j int) bool { return byName(diff.Added[i], diff.Added[j]) })
+	sort.Slice(diff.Removed, func(i, j int) bool { return byName(diff.Removed[i], diff.Removed[j]) })
+	sort.Slice(diff.Changed, func(i, j int) bool { return byName(diff.Changed[i].After, diff.Changed[j].After) })
+	return diff
+}
+
+// changeKind classifies a status change
+func changeKind(before, after string) string {
+	switch {
+	case after == StatusArchived:
+		return ChangeArchived
+	case after == StatusInactive && before == StatusActive:
+		return ChangeNewlyInactive
+	case after == StatusActive && before == StatusInactive:
+		return ChangeActiveAgain
+	}
+	return ChangeStatus
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// RenderMarkdown writes a markdown summary of the entries: the number of
+// repositories per status, followed by a list of the repositories in each
+// status other than active
+func RenderMarkdown(w io.Writer, entries []Entry) error {
+	byStatus := make(map[string][]Entry)
+	var statuses []string
+	for _, entry := range entries {
+		if _, ok := byStatus[entry.Status]; !ok {
+			statuses = append(statuses, entry.Status)
+		}
+		byStatus[entry.Status] = append(byStatus[entry.Status], entry)
+	}
+	sort.Strings(statuses)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d repositories considered", len(entries))
+	for _, status := range statuses {
+		fmt.Fprintf(&buf, ", %d %s", len(byStatus[status]), status)
+	}
+	buf.WriteString(".\n")
+	for _, status := range statuses {
+		if status == StatusActive {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n### %s\n\n", status)
+		for _, entry := range byStatus[status] {
+			fmt.Fprintf(&buf, "- %s/%s (last activity %s, %s)",
+				entry.Owner, entry.Name, entry.LastActivity.Format("2006-01-02"), util.FormatBytes(int64(entry.SizeKB)*1024))
+			if entry.Signal != "" {
+				fmt.Fprintf(&buf, ": %s", entry.Signal)
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// PostFunc publishes a titled markdown post, such as a team discussion
+type PostFunc func(ctx context.Context, title, body string) error
+
+// DiscussionReporter posts a markdown summary of each report
+type DiscussionReporter struct {
+	post PostFunc
+}
+
+// NewDiscussionReporter creates a reporter publishing summaries through post
+func NewDiscussionReporter(post PostFunc) *DiscussionReporter {
+	return &DiscussionReporter{post: post}
+}
+
+// Report posts a summary of the entries, titled with the number archived
+func (r *DiscussionReporter) Report(ctx context.Context, entries []Entry) error {
+	archived := 0
+	for _, entry := range entries {
+		if entry.Status == StatusArchived {
+			archived++
+		}
+	}
+	var body bytes.Buffer
+	if err := RenderMarkdown(&body, entries); err != nil {
+		return err
+	}
+	title := fmt.Sprintf("Repository archiving run %s: %d archived", time.Now().Format("2006-01-02"), archived)
+	return r.post(ctx, title, body.String())
+}
@@ -0,0 +1,170 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/eyedeekay/github-archiver/pkg/util"
+)
+
+// Repository statuses used in report entries
+const (
+	StatusInactive    = "inactive"
+	StatusActive      = "active"
+	StatusArchived    = "archived"
+	StatusFailed      = "failed"
+	StatusTested      = "tested"
+	StatusQuarantined = "quarantined"
+	// StatusForkDisabled marks repositories left untouched because they
+	// cannot be forked
+	StatusForkDisabled = "skipped-fork-disabled"
+	// StatusMissingTopic marks repositories the delete strategy left
+	// untouched because they lack the -require-topic approval
+	StatusMissingTopic = "skipped-missing-topic"
+	// StatusTimedOut marks repositories abandoned after -repo-timeout
+	StatusTimedOut = "timed-out"
+	// StatusNotified marks candidates within the -notify-grace period of
+	// their notice issue
+	StatusNotified = "notified"
+	// StatusObjected marks candidates whose notice issue was labeled with
+	// the objection label or closed
+	StatusObjected = "objected"
+	// StatusReview marks repositories held back because their activity
+	// timestamps are implausible, to be checked by hand
+	StatusReview = "review"
+	// StatusSignatureMismatch marks repositories whose fork did not keep
+	// the signature verification of their head commit
+	StatusSignatureMismatch = "signature-mismatch"
+	// StatusDeferred marks candidates left for a later run when
+	// -max-runtime ran out
+	StatusDeferred = "deferred"
+)
+
+// Reconciliation statuses used when comparing a namespace with its archive
+const (
+	StatusReconciled  = "reconciled"
+	StatusSourceOnly  = "source-only"
+	StatusArchiveOnly = "archive-only"
+	StatusMismatch    = "mismatch"
+)
+
+// Entry describes the outcome of a run for a single repository
+type Entry struct {
+	Owner        string    `json:"owner"`
+	Name         string    `json:"name"`
+	LastActivity time.Time `json:"last_activity"`
+	Status       string    `json:"status"`
+	Signal       string    `json:"signal,omitempty"` // what decided the status
+	Score        float64   `json:"score,omitempty"`  // archivability, when scoring
+	SizeKB       int       `json:"size_kb"`
+}
+
+// ParseTemplate parses a report template. A value starting with '@' is
+// treated as the path of a file containing the template.
+func ParseTemplate(value string) (*template.Template, error) {
+	text := value
+	if strings.HasPrefix(value, "@") {
+		data, err := os.ReadFile(value[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report template: %w", err)
+		}
+		text = string(data)
+	}
+
+	tmpl, err := template.New("report").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report template: %w", err)
+	}
+
+	// Execute against an empty entry so unknown fields are caught up front
+	if err := tmpl.Execute(io.Discard, Entry{}); err != nil {
+		return nil, fmt.Errorf("invalid report template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// RenderTemplate renders each entry through the template, one line per entry
+func RenderTemplate(w io.Writer, tmpl *template.Template, entries []Entry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		buf.Reset()
+		if err := tmpl.Execute(&buf, entry); err != nil {
+			return fmt.Errorf("failed to render report for %s/%s: %w", entry.Owner, entry.Name, err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	return nil
+}
+
+// maxNameWidth is the widest repository name rendered in a table
+const maxNameWidth = 40
+
+// RenderTable writes the entries as an aligned table with columns for the
+// repository, last activity, days inactive, size, status and deciding
+// signal. A score column is added when any entry was scored, and a footer
+// with the estimated storage reclaimed when any entry was archived.
+func RenderTable(w io.Writer, entries []Entry, now time.Time) error {
+	scored := false
+	for _, entry := range entries {
+		if entry.Score > 0 {
+			scored = true
+			break
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "REPOSITORY\tLAST ACTIVITY\tINACTIVE DAYS\tSIZE\tSTATUS\tSIGNAL"
+	if scored {
+		header += "\tSCORE"
+	}
+	fmt.Fprintln(tw, header)
+	var reclaimed int64
+	archived := 0
+	for _, entry := range entries {
+		days := int(now.Sub(entry.LastActivity).Hours() / 24)
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s",
+			truncate(entry.Owner+"/"+entry.Name, maxNameWidth),
+			entry.LastActivity.Format("2006-01-02"),
+			days,
+			util.FormatBytes(int64(entry.SizeKB)*1024),
+			entry.Status,
+			entry.Signal)
+		if scored {
+			fmt.Fprintf(tw, "\t%.0f", entry.Score)
+		}
+		fmt.Fprintln(tw)
+		if entry.Status == StatusArchived {
+			reclaimed += int64(entry.SizeKB) * 1024
+			archived++
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if archived > 0 {
+		if _, err := fmt.Fprintf(w, "Estimated storage reclaimed: %s across %d archived repositories\n", util.FormatBytes(reclaimed), archived); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most width runes, marking the cut with "..."
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-3]) + "..."
+}
@@ -0,0 +1,126 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+)
+
+// WebhookTimeout bounds how long delivering a report to a webhook may take
+const WebhookTimeout = 30 * time.Second
+
+// Reporter delivers the entries of a run to a destination
+type Reporter interface {
+	Report(ctx context.Context, entries []Entry) error
+}
+
+// Format renders entries to a writer
+type Format func(w io.Writer, entries []Entry) error
+
+// TableFormat renders entries as an aligned table, relative to the current time
+func TableFormat(w io.Writer, entries []Entry) error {
+	return RenderTable(w, entries, time.Now())
+}
+
+// TemplateFormat renders each entry through a template
+func TemplateFormat(tmpl *template.Template) Format {
+	return func(w io.Writer, entries []Entry) error {
+		return RenderTemplate(w, tmpl, entries)
+	}
+}
+
+// RenderJSON writes the entries as a single JSON array
+func RenderJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(entries); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// WriterReporter renders reports to a writer such as stdout
+type WriterReporter struct {
+	w      io.Writer
+	format Format
+}
+
+// NewWriterReporter creates a reporter rendering to w in the given format
+func NewWriterReporter(w io.Writer, format Format) *WriterReporter {
+	return &WriterReporter{w: w, format: format}
+}
+
+// Report renders the entries to the writer
+func (r *WriterReporter) Report(ctx context.Context, entries []Entry) error {
+	return r.format(r.w, entries)
+}
+
+// FileReporter renders reports to a file, replacing its contents
+type FileReporter struct {
+	path   string
+	format Format
+}
+
+// NewFileReporter creates a reporter rendering to the file at path
+func NewFileReporter(path string, format Format) *FileReporter {
+	return &FileReporter{path: path, format: format}
+}
+
+// Report renders the entries to the file
+func (r *FileReporter) Report(ctx context.Context, entries []Entry) error {
+	f, err := os.Create(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	if err := r.format(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WebhookReporter POSTs reports as JSON to an HTTP endpoint
+type WebhookReporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookReporter creates a reporter posting to url
+func NewWebhookReporter(url string) *WebhookReporter {
+	return &WebhookReporter{url: url, client: http.DefaultClient}
+}
+
+// Report posts the entries as a JSON array
+func (r *WebhookReporter) Report(ctx context.Context, entries []Entry) error {
+	var body bytes.Buffer
+	if err := RenderJSON(&body, entries); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post report: unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrorKind categorizes a failure so callers can tell user mistakes apart
+// from problems with GitHub or the tool itself
+type ErrorKind int
+
+const (
+	InternalError ErrorKind = iota
+	UsageError
+	AuthError
+	TransientError
+	RefusedError
+)
+
+var kindNames = map[ErrorKind]string{
+	InternalError:  "internal error",
+	UsageError:     "usage error",
+	AuthError:      "authentication error",
+	TransientError: "transient error",
+	RefusedError:   "refused by a safety limit",
+}
+
+// String returns a human readable name for the kind
+func (k ErrorKind) String() string {
+	return kindNames[k]
+}
+
+// ExitCode returns the process exit code used for the kind
+func (k ErrorKind) ExitCode() int {
+	switch k {
+	case UsageError:
+		return 2
+	case AuthError:
+		return 3
+	case TransientError:
+		return 4
+	case RefusedError:
+		return 5
+	default:
+		return 1
+	}
+}
+
+// Error is an error tagged with an ErrorKind
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError tags err with the given kind. A nil err yields nil.
+func NewError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Usagef creates a usage error from a format string
+func Usagef(format string, args ...interface{}) error {
+	return &Error{Kind: UsageError, Err: fmt.Errorf(format, args...)}
+}
+
+// KindOf returns the kind of the first categorized error in err's chain.
+// The bool is false if err carries no category.
+func KindOf(err error) (ErrorKind, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind, true
+	}
+	return InternalError, false
+}
+
+// MultiError collects the errors of independent operations, such as workers
+// processing one repository each, so that one failure does not hide the
+// rest. It is safe for concurrent use, and the zero value is empty.
+type MultiError struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// Add records an error. A nil err is ignored, and the errors of another
+// MultiError are added one by one.
+func (m *MultiError) Add(err error) {
+	if err == nil {
+		return
+	}
+	errs := []error{err}
+	if other, ok := err.(*MultiError); ok {
+		errs = other.Unwrap()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errs = append(m.errs, errs...)
+}
+
+// Len returns the number of errors recorded. A nil MultiError is empty.
+func (m *MultiError) Len() int {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.errs)
+}
+
+// Error implements the error interface, joining the messages of the
+// recorded errors in the order they were added
+func (m *MultiError) Error() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.errs) == 1 {
+		return m.errs[0].Error()
+	}
+	msgs := make([]string, len(m.errs))
+	for i, err := range m.errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(m.errs), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns a copy of the recorded errors, so errors.Is and errors.As
+// match any of them
+func (m *MultiError) Unwrap() []error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]error(nil), m.errs...)
+}
+
+// ErrorOrNil returns m if any error was recorded, and nil otherwise, so a
+// MultiError can be returned where an error is expected
+func (m *MultiError) ErrorOrNil() error {
+	if m.Len() == 0 {
+		return nil
+	}
+	return m
+}
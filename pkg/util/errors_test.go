@@ -0,0 +1,89 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestMultiErrorEmpty(t *testing.T) {
+	var m MultiError
+	m.Add(nil)
+	if m.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", m.Len())
+	}
+	if err := m.ErrorOrNil(); err != nil {
+		t.Errorf("ErrorOrNil() = %v, want nil", err)
+	}
+
+	var missing *MultiError
+	if missing.Len() != 0 || missing.ErrorOrNil() != nil {
+		t.Error("a nil MultiError is not empty")
+	}
+}
+
+func TestMultiErrorError(t *testing.T) {
+	var m MultiError
+	m.Add(errors.New("acme/app: not found"))
+	if got, want := m.Error(), "acme/app: not found"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	m.Add(errors.New("acme/tool: timed out"))
+	if got, want := m.Error(), "2 errors: acme/app: not found; acme/tool: timed out"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestMultiErrorUnwrap(t *testing.T) {
+	var m MultiError
+	m.Add(fmt.Errorf("acme/app: %w", io.ErrUnexpectedEOF))
+	m.Add(NewError(TransientError, errors.New("rate limited")))
+	err := m.ErrorOrNil()
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("errors.Is does not find a collected error")
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		t.Error("errors.Is finds an error that was not collected")
+	}
+	if kind, ok := KindOf(err); !ok || kind != TransientError {
+		t.Errorf("KindOf() = %v, %v; want %v", kind, ok, TransientError)
+	}
+
+	errs := m.Unwrap()
+	errs[0] = nil
+	if m.Unwrap()[0] == nil {
+		t.Error("Unwrap() returned the internal slice")
+	}
+}
+
+func TestMultiErrorAddFlattens(t *testing.T) {
+	var inner, outer MultiError
+	inner.Add(errors.New("a"))
+	inner.Add(errors.New("b"))
+	outer.Add(errors.New("c"))
+	outer.Add(&inner)
+	if outer.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", outer.Len())
+	}
+}
+
+func TestMultiErrorConcurrentAdd(t *testing.T) {
+	var m MultiError
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.Add(fmt.Errorf("worker %d", i))
+			_ = m.Len()
+		}(i)
+	}
+	wg.Wait()
+	if m.Len() != 100 {
+		t.Errorf("Len() = %d, want 100", m.Len())
+	}
+}
@@ -1,15 +1,114 @@
 package util
 
-import "log"
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
 
+// FORCE_PROCESSING makes ForceProcessing let callers carry on; set by -force
 var FORCE_PROCESSING = false
 
+// ContinuanceHook receives the errors logged by ForceProcessing in place of
+// the standard logger. The util package cannot use the application logger
+// directly, so callers install a hook that does.
+var ContinuanceHook func(error)
+
+// ForceProcessing logs a non-nil error and reports whether the caller should stop
 func ForceProcessing(e error) bool {
 	if e != nil {
-		log.Printf("ERROR CONTINUANCE: %v", e)
+		if ContinuanceHook != nil {
+			ContinuanceHook(e)
+		} else {
+			log.Printf("ERROR CONTINUANCE: %v", e)
+		}
 	}
 	if FORCE_PROCESSING {
 		return false
 	}
 	return e != nil
 }
+
+// SleepCtx pauses for the given duration, returning early with the context's
+// error if it is canceled first
+func SleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ParseDuration parses a positive duration like time.ParseDuration,
+// additionally accepting whole day ("30d"), week ("2w") and year ("2y") units
+func ParseDuration(s string) (time.Duration, error) {
+	d, err := parseUnit(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q: must be positive", s)
+	}
+	return d, nil
+}
+
+// parseUnit parses a whole number of one of the extra units, deferring to
+// time.ParseDuration for anything else
+func parseUnit(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+	if len(s) < 2 {
+		return time.ParseDuration(s)
+	}
+	unit, ok := units[s[len(s)-1:]]
+	if !ok {
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// FormatBytes formats a byte count with a binary unit, e.g. "512 KB" or
+// "1.5 GB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	units := []string{"KB", "MB", "GB", "TB"}
+	if value < 10 {
+		return fmt.Sprintf("%.1f %s", value, units[exp])
+	}
+	return fmt.Sprintf("%.0f %s", value, units[exp])
+}
+
+// Clock provides the current time, so cutoffs can be tested at fixed times
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock backed by time.Now
+type SystemClock struct{}
+
+// Now returns the current system time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
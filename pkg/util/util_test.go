@@ -0,0 +1,134 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForceProcessing(t *testing.T) {
+	failure := errors.New("listing failed")
+	tests := []struct {
+		name  string
+		err   error
+		force bool
+		stop  bool
+	}{
+		{"nil error, force off", nil, false, false},
+		{"error, force off", failure, false, true},
+		{"nil error, force on", nil, true, false},
+		{"error, force on", failure, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(force bool, hook func(error)) {
+				FORCE_PROCESSING, ContinuanceHook = force, hook
+			}(FORCE_PROCESSING, ContinuanceHook)
+
+			var logged []error
+			FORCE_PROCESSING = tt.force
+			ContinuanceHook = func(err error) { logged = append(logged, err) }
+
+			if got := ForceProcessing(tt.err); got != tt.stop {
+				t.Errorf("ForceProcessing() = %v, want %v", got, tt.stop)
+			}
+			switch {
+			case tt.err == nil && len(logged) != 0:
+				t.Errorf("nil error logged: %v", logged)
+			case tt.err != nil && (len(logged) != 1 || logged[0] != tt.err):
+				t.Errorf("logged %v, want [%v]", logged, tt.err)
+			}
+		})
+	}
+}
+
+func TestForceProcessingStandardLogger(t *testing.T) {
+	defer func(hook func(error)) { ContinuanceHook = hook }(ContinuanceHook)
+	defer log.SetOutput(log.Writer())
+	ContinuanceHook = nil
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+
+	ForceProcessing(nil)
+	if buf.Len() != 0 {
+		t.Errorf("nil error logged: %q", buf.String())
+	}
+	ForceProcessing(errors.New("listing failed"))
+	if !strings.Contains(buf.String(), "ERROR CONTINUANCE: listing failed") {
+		t.Errorf("log = %q, want the error", buf.String())
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"2y", 2 * 365 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"0d", 0, true},
+		{"0", 0, true},
+		{"0s", 0, true},
+		{"-1y", 0, true},
+		{"-5m", 0, true},
+		{"300000y", 0, true},
+		{"99999999999999999999d", 0, true},
+		{"9999999999h", 0, true},
+		{"y", 0, true},
+		{"", 0, true},
+		{"2x", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSleepCtxCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := SleepCtx(ctx, time.Minute)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("SleepCtx() = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("SleepCtx() returned after %v, want promptly after cancellation", elapsed)
+	}
+}
+
+func TestSleepCtxAlreadyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := SleepCtx(ctx, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("SleepCtx() = %v, want context.Canceled", err)
+	}
+}
+
+func TestSleepCtxDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := SleepCtx(ctx, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SleepCtx() = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestSleepCtxElapses(t *testing.T) {
+	if err := SleepCtx(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("SleepCtx() = %v, want nil", err)
+	}
+}